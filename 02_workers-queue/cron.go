@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A parsed cron expression, in the classic five fields format:
+//
+//	minute hour day-of-month month day-of-week
+//
+// Each field can be a '*', a value, a range ('1-5'), a step ('*/15' or
+// '0-30/10') or a comma separated list of them. The day of week goes
+// from 0 (Sunday) to 6, 7 is accepted as Sunday too. The '@hourly',
+// '@daily', '@weekly', '@monthly' and '@yearly' shortcuts are accepted.
+type cronSchedule struct {
+	minutes  [60]bool
+	hours    [24]bool
+	days     [32]bool
+	months   [13]bool
+	weekdays [7]bool
+
+	// When both the day of month and the day of week are restricted
+	// (not starting with a '*'), a day matches if any of them matches,
+	// otherwise both must match (like the classic cron: '*/2' is not a
+	// restriction, so '0 0 */2 * 1' runs on the odd days that are Mondays).
+	anyDay     bool
+	anyWeekday bool
+}
+
+var cronShortcuts = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+func parseCron(expr string) (*cronSchedule, error) {
+	if e, ok := cronShortcuts[expr]; ok {
+		expr = e
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression '%s': expected 5 fields", expr)
+	}
+
+	s := &cronSchedule{
+		anyDay:     strings.HasPrefix(fields[2], "*"),
+		anyWeekday: strings.HasPrefix(fields[4], "*"),
+	}
+
+	var weekdays [8]bool
+	for _, f := range []struct {
+		field    string
+		min, max int
+		set      []bool
+	}{
+		{fields[0], 0, 59, s.minutes[:]},
+		{fields[1], 0, 23, s.hours[:]},
+		{fields[2], 1, 31, s.days[:]},
+		{fields[3], 1, 12, s.months[:]},
+		{fields[4], 0, 7, weekdays[:]},
+	} {
+		err := parseCronField(f.field, f.min, f.max, f.set)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression '%s': %s", expr, err)
+		}
+	}
+	copy(s.weekdays[:], weekdays[:7])
+	s.weekdays[0] = s.weekdays[0] || weekdays[7]
+
+	return s, nil
+}
+
+func parseCronField(field string, min, max int, set []bool) error {
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid step in '%s'", part)
+			}
+			rng, step = part[:i], n
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+			var err error
+			lo, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return fmt.Errorf("invalid value in '%s'", part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				hi, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return fmt.Errorf("invalid value in '%s'", part)
+				}
+			} else if step > 1 {
+				// A step after a single value, like '5/15',
+				// means from that value to the maximum.
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return fmt.Errorf("'%s' out of range [%d-%d]", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return nil
+}
+
+func (s *cronSchedule) matchDay(t time.Time) bool {
+	day, weekday := s.days[t.Day()], s.weekdays[t.Weekday()]
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+// The first activation time strictly after t (truncated to the minute).
+// The zero time is returned if there's no activation in the next 5 years
+// (e.g. for '0 0 30 2 *', the 30th of February).
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !s.months[t.Month()] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
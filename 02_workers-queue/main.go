@@ -3,20 +3,76 @@ package main
 import (
 	"flag"
 	"log"
+	"time"
 )
 
-const help = "choose between 'worker' or 'producer'"
+const (
+	helpMode         = "choose between 'worker', 'producer', 'supervisor', 'scheduler' or 'control'"
+	helpCommand      = "control command: 'status', 'pause', 'resume', 'drain' or 'concurrency'"
+	helpConcurrency  = "number of workers requested with the 'concurrency' control command"
+	helpWorkers      = "number of concurrent workers (goroutines) started in worker mode"
+	helpOutbox       = "path of the local outbox file, used by the producer if set"
+	helpSchedule     = "path of the JSON schedule definition used by the scheduler"
+	helpLimits       = "path of a JSON file with the per-task rate limits (reloaded on SIGHUP)"
+	helpMinWorkers   = "minimum number of workers kept by the supervisor"
+	helpMaxWorkers   = "maximum number of workers started by the supervisor"
+	helpInterval     = "interval between two inspections of the queue"
+	helpScaleUpAt    = "scale up when the ready messages per consumer are above this value"
+	helpScaleDownAt  = "scale down when the ready messages per consumer are below this value"
+	helpStableChecks = "consecutive checks above/below the thresholds needed to scale"
+	helpMetricsAddr  = "address where the supervisor metrics are served (e.g. ':8080')"
+)
 
 func main() {
-	mode := flag.String("mode", "producer", help)
+	mode := flag.String("mode", "producer", helpMode)
+	workers := flag.Int("workers", 1, helpWorkers)
+	outbox := flag.String("outbox", "", helpOutbox)
+	schedule := flag.String("schedule", "schedule.json", helpSchedule)
+	limits := flag.String("limits", "", helpLimits)
+	command := flag.String("command", "status", helpCommand)
+	concurrency := flag.Int("concurrency", 0, helpConcurrency)
+
+	cfg := supervisorConfig{}
+	flag.IntVar(&cfg.minWorkers, "min-workers", 1, helpMinWorkers)
+	flag.IntVar(&cfg.maxWorkers, "max-workers", 10, helpMaxWorkers)
+	flag.DurationVar(&cfg.interval, "check-interval", 5*time.Second, helpInterval)
+	flag.Float64Var(&cfg.scaleUpAt, "scale-up-at", 5, helpScaleUpAt)
+	flag.Float64Var(&cfg.scaleDownAt, "scale-down-at", 1, helpScaleDownAt)
+	flag.IntVar(&cfg.stableChecks, "stable-checks", 3, helpStableChecks)
+	flag.StringVar(&cfg.metricsAddr, "metrics-addr", "", helpMetricsAddr)
 	flag.Parse()
 
 	switch *mode {
 	case "producer":
-		producer()
+		if *outbox != "" {
+			outboxProducer(*outbox)
+		} else {
+			producer()
+		}
 	case "worker":
-		worker()
+		if *workers < 1 {
+			log.Fatalf("invalid number of workers: %d", *workers)
+		}
+		worker(*workers, *limits)
+	case "supervisor":
+		if cfg.minWorkers < 0 || cfg.maxWorkers < cfg.minWorkers || cfg.maxWorkers < 1 {
+			log.Fatalf("invalid workers bounds: min %d, max %d", cfg.minWorkers, cfg.maxWorkers)
+		}
+		if cfg.scaleDownAt > cfg.scaleUpAt {
+			log.Fatalf("scale down threshold above scale up threshold")
+		}
+		if cfg.interval <= 0 {
+			log.Fatalf("invalid check interval: %s", cfg.interval)
+		}
+		if cfg.stableChecks < 1 {
+			log.Fatalf("invalid number of stable checks: %d", cfg.stableChecks)
+		}
+		supervisor(cfg, *limits)
+	case "scheduler":
+		scheduler(*schedule)
+	case "control":
+		control(controlCommand{Command: *command, Concurrency: *concurrency})
 	default:
-		log.Fatalf(help)
+		log.Fatalf(helpMode)
 	}
 }
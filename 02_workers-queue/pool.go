@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// A pool of workers sharing the same connection and rate limiter. The
+// number of workers can be changed at runtime, each worker is a goroutine
+// consuming from the tasks queue with its own channel and consumer tag.
+type workerPool struct {
+	conn    *amqp.Connection
+	limiter *rateLimiter
+
+	mu      sync.Mutex
+	workers []*poolWorker
+	nextID  int
+	wg      sync.WaitGroup
+}
+
+type poolWorker struct {
+	id      int
+	tag     string
+	channel *amqp.Channel
+}
+
+func newWorkerPool(conn *amqp.Connection, limiter *rateLimiter) *workerPool {
+	return &workerPool{conn: conn, limiter: limiter}
+}
+
+// The number of running workers.
+func (p *workerPool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.workers)
+}
+
+// Start or stop workers until exactly n workers are running. Stopped
+// workers complete (and ack) the task in progress before exiting.
+func (p *workerPool) resize(n int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for len(p.workers) < n {
+		w, err := p.start()
+		if err != nil {
+			return err
+		}
+		p.workers = append(p.workers, w)
+	}
+	for len(p.workers) > n {
+		w := p.workers[len(p.workers)-1]
+		p.workers = p.workers[:len(p.workers)-1]
+		err := p.stop(w)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Wait until all the workers started so far are stopped.
+func (p *workerPool) wait() {
+	p.wg.Wait()
+}
+
+func (p *workerPool) start() (*poolWorker, error) {
+	id := p.nextID
+	p.nextID++
+
+	tag := fmt.Sprintf("worker-%d", id)
+	channel, messages, err := consumeTasks(p.conn, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer channel.Close()
+		processTasks(id, messages, p.limiter)
+		log.Printf("[%d] Worker stopped", id)
+	}()
+
+	log.Printf("[%d] Worker started", id)
+	return &poolWorker{id: id, tag: tag, channel: channel}, nil
+}
+
+// Cancelling the consumer tells the broker to stop delivering messages to
+// the worker, and closes its deliveries channel. The worker goroutine then
+// completes the task in progress and closes the channel. Deliveries still
+// unacked at that point (if any) are re-enqueued by the broker.
+func (p *workerPool) stop(w *poolWorker) error {
+	return w.channel.Cancel(w.tag, false)
+}
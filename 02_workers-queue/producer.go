@@ -10,6 +10,10 @@ import (
 
 const queueName = "task_queue"
 
+// The names of the tasks sent by the producer. Workers
+// can apply different rate limits to each of them.
+var taskNames = []string{"hello", "email", "thumbnail"}
+
 func producer() {
 
 	// Establish the connection and create the communication channel.
@@ -46,7 +50,7 @@ func producer() {
 	// is declared as durable).
 	for i := 0; i < 30; i++ {
 		taskBytes, err := json.Marshal(task{
-			Name:  "hello",
+			Name:  taskNames[i%len(taskNames)],
 			Level: i,
 		})
 		if err != nil {
@@ -65,3 +69,46 @@ func producer() {
 	}
 
 }
+
+// Like the producer, but the tasks are first written to a local durable
+// outbox and then published by a relay goroutine. The producer doesn't
+// depend on the broker availability: if the broker is down the tasks are
+// kept in the outbox, and they are published when it comes back (even
+// after a producer restart, since the outbox is stored on disk).
+func outboxProducer(path string) {
+
+	box, err := openOutbox(path)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+	defer box.close()
+
+	log.Printf("Unsent tasks in the outbox: %d\n", len(box.unsent()))
+
+	stop := make(chan struct{})
+	relayDone := make(chan struct{})
+	go func() {
+		relay(box, stop)
+		close(relayDone)
+	}()
+
+	for i := 0; i < 30; i++ {
+		taskBytes, err := json.Marshal(task{
+			Name:  taskNames[i%len(taskNames)],
+			Level: i,
+		})
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+		_, err = box.add(taskBytes)
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+		log.Printf("Stored '%s'", string(taskBytes))
+		time.Sleep(time.Second)
+	}
+
+	// Wait for the relay to publish the remaining tasks.
+	close(stop)
+	<-relayDone
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// A rate limit, expressed as a sustained rate (tasks per second)
+// and a burst size (tasks that can be started back to back).
+type limit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// The rate limits configuration. The global limit (optional) is
+// applied to all the tasks, the per-task limits are applied to the
+// tasks with the matching name. Example:
+//
+//	{
+//		"global": { "rate": 5, "burst": 5 },
+//		"tasks": { "email": { "rate": 0.5, "burst": 1 } }
+//	}
+type limitsConfig struct {
+	Global *limit           `json:"global"`
+	Tasks  map[string]limit `json:"tasks"`
+}
+
+func loadLimits(path string) (limitsConfig, error) {
+	var cfg limitsConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	err = json.Unmarshal(data, &cfg)
+	if err != nil {
+		return cfg, err
+	}
+	if cfg.Global != nil {
+		err = cfg.Global.validate()
+		if err != nil {
+			return cfg, fmt.Errorf("global limit: %s", err)
+		}
+	}
+	for task, l := range cfg.Tasks {
+		err = l.validate()
+		if err != nil {
+			return cfg, fmt.Errorf("limit of task '%s': %s", task, err)
+		}
+	}
+	return cfg, nil
+}
+
+// A zero rate would never refill the bucket, blocking the tasks forever.
+func (l limit) validate() error {
+	if l.Rate <= 0 {
+		return fmt.Errorf("invalid rate: %g", l.Rate)
+	}
+	if l.Burst < 0 {
+		return fmt.Errorf("invalid burst: %d", l.Burst)
+	}
+	return nil
+}
+
+// A classic token bucket. Tokens are added at a constant rate up to
+// the burst size, and every task consumes one token before starting.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(l limit, now time.Time) *tokenBucket {
+	burst := math.Max(float64(l.Burst), 1)
+	return &tokenBucket{rate: l.Rate, burst: burst, tokens: burst, last: now}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// Time to wait until a token is available. A zero
+// duration means that a token can be taken right now.
+func (b *tokenBucket) delay() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	if b.rate <= 0 {
+		return time.Second
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// The rate limiter is shared among all the worker goroutines of the
+// process, so that the limits are enforced per process and not per
+// goroutine. The buckets can be replaced at runtime with load.
+type rateLimiter struct {
+	mu     sync.Mutex
+	global *tokenBucket
+	tasks  map[string]*tokenBucket
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{tasks: map[string]*tokenBucket{}}
+}
+
+// Replace the current limits with the provided ones. Tokens already
+// accumulated by existing buckets are kept (capped to the new burst),
+// so that a reload doesn't grant a fresh burst to every task.
+func (r *rateLimiter) load(cfg limitsConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	replace := func(old *tokenBucket, l limit) *tokenBucket {
+		b := newTokenBucket(l, now)
+		if old != nil {
+			old.refill(now)
+			b.tokens = math.Min(b.burst, old.tokens)
+		}
+		return b
+	}
+
+	if cfg.Global != nil {
+		r.global = replace(r.global, *cfg.Global)
+	} else {
+		r.global = nil
+	}
+
+	tasks := map[string]*tokenBucket{}
+	for name, l := range cfg.Tasks {
+		tasks[name] = replace(r.tasks[name], l)
+	}
+	r.tasks = tasks
+}
+
+// Block until the task with the provided name is allowed to start, and
+// return the time spent waiting. A token is taken from both the global
+// and the per-task bucket at the same time, or from none of them, so a
+// waiting task never holds tokens that other tasks could use.
+func (r *rateLimiter) wait(name string) time.Duration {
+	var held time.Duration
+	for {
+		r.mu.Lock()
+		now := time.Now()
+
+		var buckets []*tokenBucket
+		if r.global != nil {
+			buckets = append(buckets, r.global)
+		}
+		if b, ok := r.tasks[name]; ok {
+			buckets = append(buckets, b)
+		}
+
+		var delay time.Duration
+		for _, b := range buckets {
+			b.refill(now)
+			if d := b.delay(); d > delay {
+				delay = d
+			}
+		}
+		if delay == 0 {
+			for _, b := range buckets {
+				b.tokens--
+			}
+			r.mu.Unlock()
+			return held
+		}
+		r.mu.Unlock()
+
+		time.Sleep(delay)
+		held += delay
+	}
+}
+
+// Create a rate limiter with the limits read from the provided file and
+// reload them on SIGHUP. An empty path means no limits at all.
+func setupLimiter(path string) (*rateLimiter, error) {
+	limiter := newRateLimiter()
+	if path == "" {
+		return limiter, nil
+	}
+	cfg, err := loadLimits(path)
+	if err != nil {
+		return nil, err
+	}
+	limiter.load(cfg)
+	go reloadLimitsOnSignal(limiter, path)
+	return limiter, nil
+}
+
+// Reload the limits from the provided file every time the process
+// receives a SIGHUP. Invalid files are reported and ignored, so the
+// previous limits stay in place.
+func reloadLimitsOnSignal(limiter *rateLimiter, path string) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		cfg, err := loadLimits(path)
+		if err != nil {
+			log.Printf("Limits not reloaded: %s", err)
+			continue
+		}
+		limiter.load(cfg)
+		log.Printf("Limits reloaded from '%s'", path)
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// The stream queue retaining the history of the logs exchange.
+const historyStream = "logs.history"
+
+// Retention is applied by the broker to whole segments of the stream, so
+// segments are kept small for the max age to be (roughly) honoured.
+const historySegmentSize = 1 << 20
+
+// How long the end of the stream is waited for, when looking for the
+// offset of the last log.
+const historyIdle = 500 * time.Millisecond
+
+// Where a late joiner starts replaying the history: the last N logs, or
+// the logs published since a point in time.
+type historyStart struct {
+	last  int
+	since time.Time
+}
+
+func (h historyStart) enabled() bool {
+	return h.last > 0 || !h.since.IsZero()
+}
+
+// Parse the start of the replay of the logs published since the provided
+// time, either a timestamp (RFC 3339) or a duration ago (e.g. '10m').
+func parseSince(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid since: '%s'", s)
+	}
+	return time.Now().Add(-d), nil
+}
+
+// A unique id for a published log, used to find the boundary between the
+// replayed history and the live logs.
+func newMessageId() string {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Declare the history stream and bind it to the logs exchange, so that
+// it retains all the logs published for the provided max age. A stream is
+// an append-only log: unlike with the other queues, consuming from it
+// doesn't remove the messages, and a consumer can start from any offset
+// (or point in time). Note that the arguments can't be changed once the
+// stream is declared.
+func declareHistory(channel *amqp.Channel, maxAge time.Duration) error {
+	_, err := channel.QueueDeclare(historyStream, true, false, false, false, amqp.Table{
+		"x-queue-type":                    "stream",
+		"x-max-age":                       fmt.Sprintf("%ds", int64(maxAge.Seconds())),
+		"x-stream-max-segment-size-bytes": int64(historySegmentSize),
+	})
+	if err != nil {
+		return err
+	}
+	return channel.QueueBind(historyStream, "", logsExchange, false, nil)
+}
+
+// Replay the history to a late joiner, then switch to the live logs. The
+// live queue must be bound before the replay starts, so no log is missed:
+// logs published since then are both in the live queue and in the stream.
+// The boundary is the first live log, found again in the stream: the replay
+// stops there, and the live logs are returned. If the replay went past the
+// boundary before the first live log was received, the live logs already
+// replayed are skipped instead, so there are no duplicates either way.
+func replayHistory(conn *amqp.Connection, start historyStart, live <-chan amqp.Delivery) (<-chan amqp.Delivery, error) {
+
+	channel, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	defer channel.Close()
+
+	_, err = channel.QueueDeclarePassive(historyStream, true, false, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("history not available (publish with --history): %s", err)
+	}
+
+	var offset interface{} = start.since
+	if start.last > 0 {
+		offset, err = historyOffset(conn, start.last)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Stream consumers must ack the messages (which are not removed anyway),
+	// and a prefetch limit is required.
+	err = channel.Qos(100, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := channel.Consume(historyStream, "", false, false, false, false, amqp.Table{
+		"x-stream-offset": offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	replayed, count := map[string]bool{}, 0
+	var first *amqp.Delivery
+	waiting := live
+	for {
+		select {
+		case message, ok := <-stream:
+			if !ok {
+				return nil, fmt.Errorf("history stream closed")
+			}
+			err = message.Ack(false)
+			if err != nil {
+				return nil, err
+			}
+			if first != nil && message.MessageId == first.MessageId {
+				log.Printf("%d logs replayed, switching to live logs", count)
+				return skipReplayed(*first, live, replayed), nil
+			}
+			if message.MessageId != "" {
+				replayed[message.MessageId] = true
+			}
+			// A timestamp offset starts from the beginning of a chunk of the
+			// stream, so a few older logs can be received.
+			if !start.since.IsZero() && message.Timestamp.Before(start.since) {
+				continue
+			}
+			log.Printf("Replayed log : [x] %s", message.Body)
+			count++
+
+		case message, ok := <-waiting:
+			if !ok {
+				return nil, fmt.Errorf("live queue closed")
+			}
+			if message.MessageId == "" || replayed[message.MessageId] {
+				if message.MessageId == "" {
+					log.Printf("Live log without id, the boundary with the history is unknown")
+				}
+				log.Printf("%d logs replayed, switching to live logs", count)
+				return skipReplayed(message, live, replayed), nil
+			}
+			first, waiting = &message, nil
+		}
+	}
+}
+
+// Find the offset of the last N logs of the stream. The offset of the
+// last log is unknown, but the 'last' offset starts from the last chunk of
+// the stream: we read it to the end, where the stream stays idle. Offsets
+// are provided with the 'x-stream-offset' header. An empty stream starts
+// from the first log (which may arrive in the meantime).
+func historyOffset(conn *amqp.Connection, last int) (interface{}, error) {
+
+	channel, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	defer channel.Close()
+
+	err = channel.Qos(100, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := channel.Consume(historyStream, "", false, false, false, false, amqp.Table{
+		"x-stream-offset": "last",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	end := int64(-1)
+	for {
+		select {
+		case message, ok := <-stream:
+			if !ok {
+				return nil, fmt.Errorf("history stream closed")
+			}
+			err = message.Ack(false)
+			if err != nil {
+				return nil, err
+			}
+			if offset, ok := message.Headers["x-stream-offset"].(int64); ok {
+				end = offset
+			}
+		case <-time.After(historyIdle):
+			if end < 0 {
+				return "first", nil
+			}
+			start := end - int64(last) + 1
+			if start < 0 {
+				start = 0
+			}
+			return start, nil
+		}
+	}
+}
+
+// Forward the live logs, starting from the provided first one, skipping
+// those at the beginning that have already been replayed.
+func skipReplayed(first amqp.Delivery, live <-chan amqp.Delivery, replayed map[string]bool) <-chan amqp.Delivery {
+	messages := make(chan amqp.Delivery)
+	go func() {
+		defer close(messages)
+		skipping := true
+		if first.MessageId == "" || !replayed[first.MessageId] {
+			skipping = false
+			messages <- first
+		}
+		for message := range live {
+			if skipping && message.MessageId != "" && replayed[message.MessageId] {
+				continue
+			}
+			skipping = false
+			messages <- message
+		}
+	}()
+	return messages
+}
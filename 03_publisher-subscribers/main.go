@@ -3,20 +3,103 @@ package main
 import (
 	"flag"
 	"log"
+	"strings"
+	"time"
+
+	"go-rabbit/filter"
+	"go-rabbit/overflow"
 )
 
-const help = "choose between 'publisher' or 'subscriber'"
+const (
+	helpMode         = "choose between 'publisher', 'tail', 'subscriber', 'sink', 'subscriptions' or 'unsubscribe'"
+	helpSubscription = "name of a durable subscription, resumed after a subscriber restart"
+	helpFiles        = "comma separated list of files followed in tail mode"
+	helpCheckpoint   = "file where the tail mode saves the read offsets"
+	helpSinkDir      = "directory where the sink writes the log files"
+	helpMaxSize      = "size in bytes after which the sink file is rotated"
+	helpMaxAge       = "age after which the sink file is rotated (0 to disable)"
+	helpCompress     = "compress the rotated sink files with gzip"
+	helpSyncEvery    = "number of logs written by the sink between two syncs to disk (max 32767)"
+	helpSyncInterval = "max time between two syncs to disk of the sink"
+	helpConfirm      = "publish with confirms, retrying the logs rejected by the broker"
+	helpHistory      = "retain the published logs in the history stream for this long (0 to disable)"
+	helpLast         = "replay the last N logs of the history before the live logs"
+	helpSince        = "replay the logs of the history since a time (RFC 3339) or a duration ago"
+	helpFilter       = "content filter of the subscriber, e.g. 'contains:error' or 'json:status>=500' (repeatable)"
+)
 
 func main() {
-	mode := flag.String("mode", "", help)
+	mode := flag.String("mode", "", helpMode)
+	subscription := flag.String("subscription", "", helpSubscription)
+	files := flag.String("files", "", helpFiles)
+	checkpoint := flag.String("checkpoint", "tail-checkpoints.json", helpCheckpoint)
+
+	cfg := sinkConfig{}
+	flag.StringVar(&cfg.dir, "sink-dir", "logs", helpSinkDir)
+	flag.Int64Var(&cfg.maxSize, "max-size", 10<<20, helpMaxSize)
+	flag.DurationVar(&cfg.maxAge, "max-age", time.Hour, helpMaxAge)
+	flag.BoolVar(&cfg.compress, "compress", false, helpCompress)
+	flag.IntVar(&cfg.syncEvery, "sync-every", 50, helpSyncEvery)
+	flag.DurationVar(&cfg.syncInterval, "sync-interval", time.Second, helpSyncInterval)
+	confirm := flag.Bool("confirm", false, helpConfirm)
+	history := flag.Duration("history", 0, helpHistory)
+	last := flag.Int("last", 0, helpLast)
+	since := flag.String("since", "", helpSince)
+	filters := &filter.Set{}
+	flag.Var(filters, "filter", helpFilter)
+
+	bounds := overflow.Bounds{}
+	bounds.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 
+	if *subscription != "" && !validateSubscription(*subscription) {
+		log.Fatalf("invalid subscription name: '%s'", *subscription)
+	}
+	if err := bounds.Validate(); err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	start := historyStart{last: *last}
+	if *since != "" {
+		t, err := parseSince(*since)
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+		start.since = t
+	}
+	if start.last < 0 || (start.last > 0 && !start.since.IsZero()) {
+		log.Fatalf("invalid history replay: choose one of --last or --since")
+	}
+	if start.enabled() && *subscription != "" {
+		log.Fatalf("history replay is for new subscribers, not for subscriptions")
+	}
+
 	switch *mode {
 	case "publisher":
-		publisher()
+		publisher(*confirm, *history)
+	case "tail":
+		if *files == "" {
+			log.Fatalf("missing files to follow")
+		}
+		tailPublisher(strings.Split(*files, ","), *checkpoint, *history)
 	case "subscriber":
-		subscriber()
+		subscriber(*subscription, bounds, start, filters)
+	case "sink":
+		if cfg.maxSize <= 0 || cfg.syncEvery <= 0 || cfg.syncInterval <= 0 {
+			log.Fatalf("invalid sink configuration")
+		}
+		if cfg.syncEvery > maxSyncEvery {
+			log.Fatalf("too many logs between two syncs: %d (max %d)", cfg.syncEvery, maxSyncEvery)
+		}
+		sink(cfg, *subscription, bounds)
+	case "subscriptions":
+		listSubscriptions()
+	case "unsubscribe":
+		if *subscription == "" {
+			log.Fatalf("missing subscription name")
+		}
+		deleteSubscription(*subscription)
 	default:
-		log.Fatalf(help)
+		log.Fatalf(helpMode)
 	}
 }
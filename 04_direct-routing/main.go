@@ -3,32 +3,96 @@ package main
 import (
 	"flag"
 	"log"
-	"math/rand"
 	"time"
+
+	"go-rabbit/filter"
+	"go-rabbit/overflow"
+	"go-rabbit/scenario"
 )
 
 const (
-	helpMode            = "choose between 'publisher' or 'subscriber'"
-	helpSevs            = "choose one or more of 'info', 'warn' or 'error' (format: info-warn-error)"
+	helpMode            = "choose between 'publisher', 'subscriber' or 'unroutable'"
+	helpSevs            = "choose one or more severities, e.g. 'debug', 'info', 'warn', 'error' or 'fatal' (format: info-warn-error)"
+	helpMinSev          = "subscribe to the logs of this severity and above (e.g. 'warn')"
+	helpSeverities      = "JSON file with the severities, ordered from the lowest to the highest"
+	helpConfirm         = "publish with confirms, retrying the logs rejected by the broker"
+	helpUnroutable      = "policy for the unroutable logs returned to the publisher: 'log', 'store' or 'retry'"
+	helpReturnsFile     = "file where the store policy appends the unroutable logs"
+	helpAlternate       = "declare the exchange with an alternate exchange, collecting the unroutable logs"
+	helpScenario        = "JSON file with the logs to publish, replayed instead of the random logs"
+	helpSeed            = "seed of the random logs, to publish the same logs again (0 for a random seed)"
+	helpSpeed           = "speed of the publisher clock, e.g. 10 to publish 10 times faster"
+	helpFilter          = "content filter of the subscriber, e.g. 'contains:stuff' or 'regex:#[0-9]*0 ' (repeatable)"
 	logsRoutingExchange = "logs-routing"
 )
 
 func main() {
 	mode := flag.String("mode", "", helpMode)
 	sev := flag.String("sevs", "", helpSevs)
+	minSev := flag.String("min-sev", "", helpMinSev)
+	severities := flag.String("severities", "", helpSeverities)
+	alternate := flag.Bool("alternate-exchange", false, helpAlternate)
+	scenarioPath := flag.String("scenario", "", helpScenario)
+	filters := &filter.Set{}
+	flag.Var(filters, "filter", helpFilter)
+
+	cfg := publisherConfig{}
+	flag.BoolVar(&cfg.confirm, "confirm", false, helpConfirm)
+	flag.StringVar(&cfg.unroutable, "unroutable", returnLog, helpUnroutable)
+	flag.StringVar(&cfg.returnsFile, "returns-file", "unroutable.jsonl", helpReturnsFile)
+	flag.Int64Var(&cfg.seed, "seed", 0, helpSeed)
+	flag.Float64Var(&cfg.speed, "speed", 1, helpSpeed)
+
+	bounds := overflow.Bounds{}
+	bounds.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 
-	rand.Seed(time.Now().Unix())
+	if err := bounds.Validate(); err != nil {
+		log.Fatalf("%s", err)
+	}
+	if *severities != "" {
+		if err := loadSeverities(*severities); err != nil {
+			log.Fatalf("%s", err)
+		}
+	}
 
 	switch *mode {
 	case "publisher":
-		publisher()
+		if cfg.speed <= 0 {
+			log.Fatalf("invalid speed: %g", cfg.speed)
+		}
+		if *scenarioPath != "" {
+			events, err := scenario.Load(*scenarioPath)
+			if err != nil {
+				log.Fatalf("%s", err)
+			}
+			cfg.scenario = events
+		} else if cfg.seed == 0 {
+			cfg.seed = time.Now().UnixNano()
+			log.Printf("Random logs with seed %d (use --seed to publish them again)", cfg.seed)
+		}
+		cfg.alternate = *alternate
+		publisher(cfg)
 	case "subscriber":
-		sevs, ok := validateSeverities(*sev)
-		if !ok {
-			log.Fatalf("invalid severity: '%s'", *sev)
+		if (*sev == "") == (*minSev == "") {
+			log.Fatalf("choose one of --sevs or --min-sev")
+		}
+		var sevs []string
+		var ok bool
+		if *minSev != "" {
+			sevs, ok = severitiesFrom(*minSev)
+			if !ok {
+				log.Fatalf("invalid severity: '%s'", *minSev)
+			}
+		} else {
+			sevs, ok = validateSeverities(*sev)
+			if !ok {
+				log.Fatalf("invalid severity: '%s'", *sev)
+			}
 		}
-		subscriber(sevs)
+		subscriber(sevs, bounds, *alternate, filters)
+	case "unroutable":
+		inspectUnroutable()
 	default:
 		log.Fatalf(helpMode)
 	}
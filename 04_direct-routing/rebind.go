@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const helpCommands = "commands: 'bind <severity>', 'unbind <severity>', 'list'"
+
+// Read commands from the standard input, one per line, to change the
+// bindings of the live subscriber queue: a severity can be bound (e.g. to
+// receive the info logs for a while) and unbound later. The bindings are
+// changed on the broker with QueueBind and QueueUnbind, while the messages
+// keep flowing. Logs of a severity already in the queue are still received
+// after the severity is unbound.
+func rebind(channel *amqp.Channel, queue string, severities []string) {
+
+	bound := map[string]bool{}
+	for _, severity := range severities {
+		bound[severity] = true
+	}
+
+	log.Printf("Type the binding %s", helpCommands)
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case fields[0] == "list" && len(fields) == 1:
+			list := []string{}
+			for _, severity := range SEVERITIES {
+				if bound[severity] {
+					list = append(list, severity)
+				}
+			}
+			log.Printf("queue '%s' bound with keys: %q", queue, list)
+
+		case fields[0] == "bind" && len(fields) == 2:
+			severity := fields[1]
+			if severityIndex(severity) < 0 {
+				log.Printf("invalid severity: '%s'", severity)
+				continue
+			}
+			if bound[severity] {
+				log.Printf("queue '%s' already bound with key: '%s'", queue, severity)
+				continue
+			}
+			err := channel.QueueBind(queue, severity, logsRoutingExchange, false, nil)
+			if err != nil {
+				log.Fatalf("%s", err)
+			}
+			bound[severity] = true
+			log.Printf("queue '%s' binded to exchange '%s' with key: '%s'", queue, logsRoutingExchange, severity)
+
+		case fields[0] == "unbind" && len(fields) == 2:
+			severity := fields[1]
+			if !bound[severity] {
+				log.Printf("queue '%s' not bound with key: '%s'", queue, severity)
+				continue
+			}
+			err := channel.QueueUnbind(queue, severity, logsRoutingExchange, nil)
+			if err != nil {
+				log.Fatalf("%s", err)
+			}
+			delete(bound, severity)
+			log.Printf("queue '%s' unbinded from exchange '%s' with key: '%s'", queue, logsRoutingExchange, severity)
+
+		default:
+			log.Printf("invalid command, %s", helpCommands)
+		}
+	}
+}
@@ -1,29 +1,84 @@
 package main
 
-import "strings"
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
 
-// The list of possible severity values.
-var SEVERITIES = [3]string{"info", "warn", "error"}
+// The list of possible severity values, ordered from the lowest to the
+// highest. It can be replaced with the severities of a config file.
+var SEVERITIES = []string{"debug", "info", "warn", "error", "fatal"}
+
+// Severities are used as routing keys, and they are '-' delimited in the
+// subscriber flag: they can't contain dashes (or dots, used by topics).
+var severityName = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+// Load the severities from a JSON file, containing the array of the names
+// ordered from the lowest to the highest severity, e.g.:
+//
+//	["debug", "info", "notice", "warn", "error", "fatal"]
+//
+// The publisher and the subscribers must use the same severities.
+func loadSeverities(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var sevs []string
+	err = json.Unmarshal(data, &sevs)
+	if err != nil {
+		return err
+	}
+	if len(sevs) == 0 {
+		return fmt.Errorf("no severities in '%s'", path)
+	}
+
+	seen := map[string]bool{}
+	for _, sev := range sevs {
+		if !severityName.MatchString(sev) || seen[sev] {
+			return fmt.Errorf("invalid severity in '%s': '%s'", path, sev)
+		}
+		seen[sev] = true
+	}
+	SEVERITIES = sevs
+	return nil
+}
+
+func severityIndex(sev string) int {
+	for i, s := range SEVERITIES {
+		if sev == s {
+			return i
+		}
+	}
+	return -1
+}
 
 // Helper functions to validate a list of '-' delimited severities.
 func validateSeverities(sevsInput string) ([]string, bool) {
 	sevs := strings.Split(sevsInput, "-")
-	if len(sevs) == 0 || len(sevs) > 3 {
+	if len(sevs) == 0 || len(sevs) > len(SEVERITIES) {
 		return nil, false
 	}
 
 	for _, sev := range sevs {
-		valid := false
-		for _, s := range SEVERITIES {
-			if sev == s {
-				valid = true
-				break
-			}
-		}
-		if !valid {
+		if severityIndex(sev) < 0 {
 			return nil, false
 		}
 	}
 
 	return sevs, true
 }
+
+// The severities from the provided one and above. A direct exchange only
+// routes on exact matches, so a threshold subscription ("warn and above")
+// needs a binding for each of these severities.
+func severitiesFrom(minSev string) ([]string, bool) {
+	i := severityIndex(minSev)
+	if i < 0 {
+		return nil, false
+	}
+	return SEVERITIES[i:], true
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"fmt"
 	"math/rand"
+	"regexp"
 	"strings"
+
+	"go-rabbit/topic"
 )
 
 // List of possible severity values.
@@ -25,38 +29,79 @@ const (
 	HASH = "#"
 )
 
-// Validate subscriber bindings.
+// Host names in routing keys: a single word (no dots).
+var hostName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,63}$`)
+
+// Validate subscriber bindings, in the format '<facility>.<severity>' or,
+// for the publisher with hosts, '<facility>.<host>.<severity>'. Any member
+// can be a wildcard, and '#' alone matches all the logs. Both the
+// facilities and severities of the publisher and the real ones of the
+// syslog receiver are accepted.
 func validateBinding(bind string) bool {
-	parts := strings.Split(bind, ".")
-	if len(parts) != 2 {
+	if topic.ValidatePattern(bind) != nil {
 		return false
 	}
 
-	ok := false
-	for _, src := range []string{NGINX, CRON, SSHD, STAR, HASH} {
-		if parts[0] == src {
-			ok = true
-			break
-		}
+	parts := strings.Split(bind, ".")
+	switch len(parts) {
+	case 1:
+		return parts[0] == HASH
+	case 2:
+		return validFacility(parts[0]) && validSeverity(parts[1])
+	case 3:
+		validHost := parts[1] == STAR || parts[1] == HASH || hostName.MatchString(parts[1])
+		return validFacility(parts[0]) && validHost && validSeverity(parts[2])
 	}
-	if !ok {
-		return false
+	return false
+}
+
+func validFacility(s string) bool {
+	for _, src := range append([]string{NGINX, CRON, SSHD, STAR, HASH}, syslogFacilities...) {
+		if s == src {
+			return true
+		}
 	}
+	return false
+}
 
-	ok = false
-	for _, sev := range []string{INFO, WARN, ERROR, STAR, HASH} {
-		if parts[1] == sev {
-			ok = true
-			break
+func validSeverity(s string) bool {
+	for _, sev := range append([]string{INFO, WARN, ERROR, STAR, HASH}, syslogSeverities...) {
+		if s == sev {
+			return true
 		}
 	}
-	if !ok {
-		return false
+	return false
+}
+
+// Parse a comma separated list of hosts for the publisher.
+func parseHosts(s string) ([]string, bool) {
+	if s == "" {
+		return nil, true
+	}
+	hosts := strings.Split(s, ",")
+	for _, host := range hosts {
+		if !hostName.MatchString(host) {
+			return nil, false
+		}
 	}
+	return hosts, true
+}
+
+// The binding keys of a subscriber, provided with one or more --bind flags.
+type bindingList []string
 
-	return true
+func (l *bindingList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *bindingList) Set(bind string) error {
+	if !validateBinding(bind) {
+		return fmt.Errorf("invalid binding: '%s'", bind)
+	}
+	*l = append(*l, bind)
+	return nil
 }
 
-func randSev() string {
-	return []string{INFO, WARN, ERROR}[rand.Intn(3)]
+func randSev(rng *rand.Rand) string {
+	return []string{INFO, WARN, ERROR}[rng.Intn(3)]
 }
@@ -3,31 +3,104 @@ package main
 import (
 	"flag"
 	"log"
-	"math/rand"
 	"time"
+
+	"go-rabbit/filter"
+	"go-rabbit/overflow"
+	"go-rabbit/scenario"
 )
 
 const (
-	helpMode          = "choose between 'publisher' or 'subscriber'"
-	helpBind          = "format '<facility>.<severity>'"
+	helpMode          = "choose between 'publisher', 'subscriber', 'aggregate' or 'syslog'"
+	helpBind          = "format '<facility>.<severity>' or '<facility>.<host>.<severity>' (repeatable)"
+	helpHosts         = "comma separated hosts of the publisher, adding the host to the routing keys"
+	helpSummary       = "print the message counts per binding at this interval, instead of the logs"
+	helpWindow        = "size of the aggregation windows"
+	helpSlide         = "interval of the aggregation summaries (default the window size, for tumbling windows)"
+	helpGroupBy       = "comma separated fields to group the aggregated logs by: 'facility', 'host', 'severity'"
+	helpUDP           = "UDP address of the syslog receiver (empty to disable)"
+	helpTCP           = "TCP address of the syslog receiver (empty to disable)"
+	helpConfirm       = "publish with confirms, retrying the logs rejected by the broker"
+	helpScenario      = "JSON file with the logs to publish, replayed instead of the random logs"
+	helpSeed          = "seed of the random logs, to publish the same logs again (0 for a random seed)"
+	helpSpeed         = "speed of the publisher clock, e.g. 10 to publish 10 times faster"
+	helpFilter        = "content filter of the subscriber, e.g. 'contains:stuff' or 'header:hostname=web1' (repeatable)"
 	logsTopicExchange = "logs-topic-exchange"
 )
 
 func main() {
 	mode := flag.String("mode", "", helpMode)
-	bind := flag.String("bind", "", helpBind)
+	bindings := bindingList{}
+	flag.Var(&bindings, "bind", helpBind)
+	hosts := flag.String("hosts", "", helpHosts)
+	summary := flag.Duration("summary", 0, helpSummary)
+	w := window{}
+	flag.DurationVar(&w.size, "window", time.Minute, helpWindow)
+	flag.DurationVar(&w.slide, "slide", 0, helpSlide)
+	groupBy := flag.String("group-by", "facility,severity", helpGroupBy)
+	udpAddr := flag.String("udp", ":5514", helpUDP)
+	tcpAddr := flag.String("tcp", ":5514", helpTCP)
+	scenarioPath := flag.String("scenario", "", helpScenario)
+	filters := &filter.Set{}
+	flag.Var(filters, "filter", helpFilter)
+
+	cfg := publisherConfig{}
+	flag.BoolVar(&cfg.confirm, "confirm", false, helpConfirm)
+	flag.Int64Var(&cfg.seed, "seed", 0, helpSeed)
+	flag.Float64Var(&cfg.speed, "speed", 1, helpSpeed)
+
+	bounds := overflow.Bounds{}
+	bounds.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 
-	rand.Seed(time.Now().Unix())
+	if err := bounds.Validate(); err != nil {
+		log.Fatalf("%s", err)
+	}
 
 	switch *mode {
 	case "publisher":
-		publisher()
+		var ok bool
+		cfg.hosts, ok = parseHosts(*hosts)
+		if !ok {
+			log.Fatalf("invalid hosts: '%s'", *hosts)
+		}
+		if cfg.speed <= 0 {
+			log.Fatalf("invalid speed: %g", cfg.speed)
+		}
+		if *scenarioPath != "" {
+			events, err := scenario.Load(*scenarioPath)
+			if err != nil {
+				log.Fatalf("%s", err)
+			}
+			cfg.scenario = events
+		} else if cfg.seed == 0 {
+			cfg.seed = time.Now().UnixNano()
+			log.Printf("Random logs with seed %d (use --seed to publish them again)", cfg.seed)
+		}
+		publisher(cfg)
 	case "subscriber":
-		if !validateBinding(*bind) {
-			log.Fatalf("invalid binding: '%s'", *bind)
+		if len(bindings) == 0 {
+			log.Fatalf("missing binding")
+		}
+		subscriber(bindings, bounds, *summary, filters)
+	case "aggregate":
+		if len(bindings) == 0 {
+			bindings = bindingList{HASH}
+		}
+		if w.slide == 0 {
+			w.slide = w.size
+		}
+		if err := w.validate(); err != nil {
+			log.Fatalf("%s", err)
+		}
+		var err error
+		w.groupBy, err = parseGroupBy(*groupBy)
+		if err != nil {
+			log.Fatalf("%s", err)
 		}
-		subscriber(*bind)
+		aggregate(bindings, bounds, w, filters)
+	case "syslog":
+		syslogReceiver(*udpAddr, *tcpAddr)
 	default:
 		log.Fatalf(helpMode)
 	}
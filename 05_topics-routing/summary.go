@@ -0,0 +1,40 @@
+package main
+
+import (
+	"log"
+
+	"go-rabbit/topic"
+)
+
+// The message counts of each binding of a subscriber. A message matching
+// more than one binding is delivered only once to the queue, but it's
+// counted for each binding it matches.
+type bindingCounts struct {
+	patterns []*topic.Pattern
+	counts   []int
+	total    int
+}
+
+func newBindingCounts(bindings []string) *bindingCounts {
+	c := &bindingCounts{counts: make([]int, len(bindings))}
+	for _, bind := range bindings {
+		c.patterns = append(c.patterns, topic.MustCompile(bind))
+	}
+	return c
+}
+
+func (c *bindingCounts) count(routingKey string) {
+	c.total++
+	for i, p := range c.patterns {
+		if p.Match(routingKey) {
+			c.counts[i]++
+		}
+	}
+}
+
+func (c *bindingCounts) print() {
+	log.Printf("%d logs received", c.total)
+	for i, p := range c.patterns {
+		log.Printf("  %-24s %d", p, c.counts[i])
+	}
+}
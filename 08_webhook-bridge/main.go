@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"strings"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	helpExchange = "exchange the bridge queue is bound to"
+	helpKind     = "type of the exchange: 'fanout', 'direct' or 'topic'"
+	helpBind     = "comma separated binding keys of the bridge queue"
+	helpQueue    = "name of the durable bridge queue"
+	helpURLs     = "comma separated URLs where the messages are POSTed"
+	helpRetries  = "attempts for each URL before dead-lettering the message"
+	helpBackoff  = "wait before the first retry, doubled at each retry"
+	helpTimeout  = "timeout of each HTTP request"
+
+	deadLetterExchange = "webhook-dead-letters"
+	deadLetterQueue    = "webhook-dead-letters"
+)
+
+func main() {
+	cfg := bridgeConfig{}
+	flag.StringVar(&cfg.exchange, "exchange", "logs-topic-exchange", helpExchange)
+	flag.StringVar(&cfg.kind, "type", amqp.ExchangeTopic, helpKind)
+	bind := flag.String("bind", "#", helpBind)
+	flag.StringVar(&cfg.queue, "queue", "webhook-bridge", helpQueue)
+	urls := flag.String("urls", "", helpURLs)
+	flag.IntVar(&cfg.retries, "retries", 5, helpRetries)
+	flag.DurationVar(&cfg.backoff, "backoff", time.Second, helpBackoff)
+	flag.DurationVar(&cfg.timeout, "timeout", 10*time.Second, helpTimeout)
+	flag.Parse()
+
+	if *urls == "" {
+		log.Fatalf("missing URLs")
+	}
+	if cfg.retries < 1 {
+		log.Fatalf("invalid retries: %d", cfg.retries)
+	}
+	switch cfg.kind {
+	case amqp.ExchangeFanout, amqp.ExchangeDirect, amqp.ExchangeTopic:
+	default:
+		log.Fatalf("invalid exchange type: '%s'", cfg.kind)
+	}
+
+	cfg.bindings = strings.Split(*bind, ",")
+	cfg.urls = strings.Split(*urls, ",")
+	bridge(cfg)
+}
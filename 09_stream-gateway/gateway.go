@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// How often an idle stream is pinged, so that proxies keep
+// it open and clients that went away are detected.
+const keepAliveInterval = 15 * time.Second
+
+type gateway struct {
+	conn *amqp.Connection
+}
+
+// A message streamed to the clients, JSON encoded.
+type streamEvent struct {
+	Exchange   string    `json:"exchange"`
+	RoutingKey string    `json:"routing_key"`
+	Timestamp  time.Time `json:"timestamp,omitempty"`
+	Body       string    `json:"body"`
+}
+
+// Stream all the logs of the fanout exchange (example 3).
+func (g *gateway) streamLogs(w http.ResponseWriter, r *http.Request) {
+	g.stream(w, r, logsExchange, amqp.ExchangeFanout, []string{""})
+}
+
+// Stream the logs of the topic exchange (example 5) matching the
+// binding keys provided with the 'bind' query parameter (one or
+// more times), e.g. '/streams/topics?bind=nginx.*&bind=*.error'.
+func (g *gateway) streamTopics(w http.ResponseWriter, r *http.Request) {
+	bindings := r.URL.Query()["bind"]
+	if len(bindings) == 0 {
+		http.Error(w, "missing 'bind' parameter", http.StatusBadRequest)
+		return
+	}
+	for _, binding := range bindings {
+		err := validateBinding(binding)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid binding: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+	g.stream(w, r, logsTopicExchange, amqp.ExchangeTopic, bindings)
+}
+
+// Stream the messages of the exchange matching the bindings, over a
+// WebSocket if the client asks for it or with Server-Sent Events.
+func (g *gateway) stream(w http.ResponseWriter, r *http.Request, exchange, kind string, bindings []string) {
+
+	channel, err := g.conn.Channel()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer channel.Close()
+
+	queue, deliveries, err := subscribe(channel, exchange, kind, bindings)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	// Exclusive queues are deleted when their connection is closed, but the
+	// connection is shared by all the clients: the queue of the client must
+	// be deleted explicitly when the client disconnects.
+	defer func() {
+		_, err := channel.QueueDelete(queue, false, false, false)
+		if err != nil {
+			log.Printf("Queue '%s' not deleted: %s", queue, err)
+		}
+		log.Printf("Client %s disconnected, queue '%s' deleted", r.RemoteAddr, queue)
+	}()
+
+	var (
+		send      func([]byte) error
+		keepAlive func() error
+		done      <-chan struct{}
+	)
+	if isWebSocket(r) {
+		ws, err := upgradeWebSocket(w, r)
+		if err != nil {
+			return
+		}
+		defer ws.close()
+		send, keepAlive, done = ws.writeText, ws.ping, ws.closed
+	} else {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming not supported", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		write := func(s string) error {
+			_, err := fmt.Fprint(w, s)
+			flusher.Flush()
+			return err
+		}
+		send = func(data []byte) error { return write(fmt.Sprintf("event: log\ndata: %s\n\n", data)) }
+		keepAlive = func() error { return write(": keep-alive\n\n") }
+		done = r.Context().Done()
+	}
+	log.Printf("Client %s connected to '%s' with bindings %q (queue '%s')", r.RemoteAddr, exchange, bindings, queue)
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case delivery, ok := <-deliveries:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(streamEvent{
+				Exchange:   delivery.Exchange,
+				RoutingKey: delivery.RoutingKey,
+				Timestamp:  delivery.Timestamp,
+				Body:       string(delivery.Body),
+			})
+			if err != nil {
+				log.Printf("%s", err)
+				continue
+			}
+			if send(data) != nil {
+				return
+			}
+		case <-ticker.C:
+			if keepAlive() != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// Declare the exchange and a new exclusive queue for the client, bound
+// with the provided binding keys, and start consuming from the queue.
+func subscribe(channel *amqp.Channel, exchange, kind string, bindings []string) (string, <-chan amqp.Delivery, error) {
+	err := channel.ExchangeDeclare(exchange, kind, true, false, false, false, nil)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// The queue is auto-delete too, so it's deleted by the broker as soon as
+	// the consumer is cancelled (e.g. when the channel is closed), even if the
+	// explicit deletion fails.
+	queue, err := channel.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, binding := range bindings {
+		err = channel.QueueBind(queue.Name, binding, exchange, false, nil)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	deliveries, err := channel.Consume(queue.Name, "", true, true, false, false, nil)
+	return queue.Name, deliveries, err
+}
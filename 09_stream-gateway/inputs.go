@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+
+	"go-rabbit/topic"
+)
+
+// Validate the bindings requested by the clients, with the shape of the
+// bindings of the topic subscribers (example 5): '<facility>.<severity>'
+// or '<facility>.<host>.<severity>', where any member can be a wildcard,
+// or '#' alone. The names of the facilities and of the severities are not
+// checked, they belong to the publishers: a binding matching no log just
+// streams nothing.
+func validateBinding(bind string) error {
+	if bind == "" {
+		return fmt.Errorf("empty binding")
+	}
+	err := topic.ValidatePattern(bind)
+	if err != nil {
+		return err
+	}
+	words := topic.Words(bind)
+	if len(words) == 2 || len(words) == 3 || bind == topic.Hash {
+		return nil
+	}
+	return fmt.Errorf("binding '%s' not '#' nor '<facility>.[<host>.]<severity>'", bind)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// The GUID used to compute the handshake accept key (RFC 6455, section 1.3).
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Frame opcodes (RFC 6455, section 5.2).
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+// Max size of the frames accepted from the clients, which
+// are expected to send control frames only (close, ping).
+const maxClientFrame = 64 * 1024
+
+// A minimal server side WebSocket connection, enough to push text messages
+// to the client. Frames received from the client are read by a background
+// goroutine, which answers pings and closes: the closed channel is closed
+// when the client goes away.
+type websocketConn struct {
+	conn   net.Conn
+	rw     *bufio.ReadWriter
+	mu     sync.Mutex
+	closed chan struct{}
+}
+
+func isWebSocket(r *http.Request) bool {
+	return strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade") &&
+		strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// Perform the opening handshake, taking over the HTTP connection.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*websocketConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || key == "" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+		http.Error(w, "invalid websocket handshake", http.StatusBadRequest)
+		return nil, fmt.Errorf("invalid websocket handshake")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, fmt.Errorf("websocket not supported")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	rw.WriteString("Upgrade: websocket\r\n")
+	rw.WriteString("Connection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	err = rw.Flush()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	ws := &websocketConn{conn: conn, rw: rw, closed: make(chan struct{})}
+	go ws.readLoop()
+	return ws, nil
+}
+
+// Write a single (final) frame. Server frames are never masked.
+func (ws *websocketConn) writeFrame(opcode byte, payload []byte) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+
+	if _, err := ws.rw.Write(header); err != nil {
+		return err
+	}
+	if _, err := ws.rw.Write(payload); err != nil {
+		return err
+	}
+	return ws.rw.Flush()
+}
+
+func (ws *websocketConn) writeText(payload []byte) error {
+	return ws.writeFrame(opText, payload)
+}
+
+func (ws *websocketConn) ping() error {
+	return ws.writeFrame(opPing, nil)
+}
+
+func (ws *websocketConn) readLoop() {
+	defer close(ws.closed)
+	for {
+		var h [2]byte
+		if _, err := io.ReadFull(ws.rw, h[:]); err != nil {
+			return
+		}
+		opcode, masked, length := h[0]&0x0F, h[1]&0x80 != 0, uint64(h[1]&0x7F)
+
+		switch length {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(ws.rw, ext[:]); err != nil {
+				return
+			}
+			length = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(ws.rw, ext[:]); err != nil {
+				return
+			}
+			length = binary.BigEndian.Uint64(ext[:])
+		}
+		if length > maxClientFrame {
+			return
+		}
+
+		var mask [4]byte
+		if masked {
+			if _, err := io.ReadFull(ws.rw, mask[:]); err != nil {
+				return
+			}
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(ws.rw, payload); err != nil {
+			return
+		}
+		if masked {
+			for i := range payload {
+				payload[i] ^= mask[i%4]
+			}
+		}
+
+		switch opcode {
+		case opClose:
+			// Echo the status code (if any) to complete the closing handshake.
+			if len(payload) > 2 {
+				payload = payload[:2]
+			}
+			ws.writeFrame(opClose, payload)
+			return
+		case opPing:
+			ws.writeFrame(opPong, payload)
+		}
+	}
+}
+
+func (ws *websocketConn) close() error {
+	return ws.conn.Close()
+}
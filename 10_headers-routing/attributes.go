@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// The attributes of the logs, sent as message headers, with their
+// possible values (any tenant name is valid).
+var attributes = map[string][]string{
+	"severity": {"info", "warn", "error"},
+	"facility": {"nginx", "cron", "sshd"},
+	"region":   {"eu", "us", "asia"},
+	"tenant":   nil,
+}
+
+// The tenants of the publisher.
+var tenants = []string{"acme", "globex", "initech"}
+
+var tenantName = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+// The 'x-match' binding argument: a message matches if all the criteria
+// (or any of them) match its headers.
+const (
+	matchAll = "all"
+	matchAny = "any"
+)
+
+// Random attributes for a published log.
+func randAttributes() amqp.Table {
+	headers := amqp.Table{}
+	for name, values := range attributes {
+		if values == nil {
+			values = tenants
+		}
+		headers[name] = values[rand.Intn(len(values))]
+	}
+	return headers
+}
+
+// Parse and validate a binding expression, a comma separated list of
+// '<attribute>=<value>' criteria, e.g. 'severity=error,region=eu'. The
+// result are the arguments of the binding: the criteria plus 'x-match'.
+func parseBinding(expr, match string) (amqp.Table, error) {
+	if match != matchAll && match != matchAny {
+		return nil, fmt.Errorf("invalid match: '%s'", match)
+	}
+	if expr == "" {
+		return nil, fmt.Errorf("no criteria")
+	}
+
+	args := amqp.Table{"x-match": match}
+	for _, criterion := range strings.Split(expr, ",") {
+		parts := strings.Split(criterion, "=")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid criterion: '%s'", criterion)
+		}
+		name, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+
+		values, ok := attributes[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown attribute: '%s'", name)
+		}
+		if !validValue(values, value) {
+			return nil, fmt.Errorf("invalid %s: '%s'", name, value)
+		}
+		// The headers of a message have a single value each: two criteria on
+		// the same attribute would never match all together.
+		if _, ok := args[name]; ok {
+			return nil, fmt.Errorf("duplicate attribute: '%s'", name)
+		}
+		args[name] = value
+	}
+	return args, nil
+}
+
+func validValue(values []string, value string) bool {
+	if values == nil {
+		return tenantName.MatchString(value)
+	}
+	for _, v := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"math/rand"
+	"time"
+)
+
+const (
+	helpMode            = "choose between 'publisher' or 'subscriber'"
+	helpBind            = "comma separated header criteria, e.g. 'severity=error,region=eu'"
+	helpMatch           = "match 'all' the criteria or 'any' of them"
+	logsHeadersExchange = "logs-headers"
+)
+
+func main() {
+	mode := flag.String("mode", "", helpMode)
+	bind := flag.String("bind", "", helpBind)
+	match := flag.String("match", matchAll, helpMatch)
+	flag.Parse()
+
+	rand.Seed(time.Now().Unix())
+
+	switch *mode {
+	case "publisher":
+		publisher()
+	case "subscriber":
+		args, err := parseBinding(*bind, *match)
+		if err != nil {
+			log.Fatalf("invalid binding: %s", err)
+		}
+		subscriber(args)
+	default:
+		log.Fatalf(helpMode)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"go-rabbit/topology"
+)
+
+const logsTopicExchange = "logs-topic-exchange"
+
+// The demo topology: the topic logs exchange of example 5 fans into two
+// per-team direct exchanges, selecting the logs of the team facilities.
+// Each team exchange routes the logs to the team queues by severity, and
+// the logs no team queue wants go to the alternate exchange of the teams,
+// collecting them in a single queue.
+//
+//	logs-topic-exchange (topic)
+//	  nginx.#          -> team-web (direct)
+//	                        nginx.error -> team-web-errors
+//	                        nginx.warn  -> team-web-warnings
+//	  cron.*, sshd.*   -> team-ops (direct)
+//	                        cron.error, sshd.error -> team-ops-errors
+//	team-web, team-ops -> team-unrouted (fanout, alternate) -> team-unrouted
+func demoTopology() *topology.Topology {
+	t := &topology.Topology{
+		Exchanges: []topology.Exchange{
+			{Name: logsTopicExchange, Type: amqp.ExchangeTopic},
+			{Name: "team-web", Type: amqp.ExchangeDirect, AlternateExchange: "team-unrouted"},
+			{Name: "team-ops", Type: amqp.ExchangeDirect, AlternateExchange: "team-unrouted"},
+			{Name: "team-unrouted", Type: amqp.ExchangeFanout},
+		},
+		Queues: []topology.Queue{
+			{Name: "team-web-errors"},
+			{Name: "team-web-warnings"},
+			{Name: "team-ops-errors"},
+			{Name: "team-unrouted"},
+		},
+		Bindings: []topology.Binding{
+			{Source: logsTopicExchange, Destination: "team-web", DestinationType: topology.DestinationExchange, Key: "nginx.#"},
+			{Source: logsTopicExchange, Destination: "team-ops", DestinationType: topology.DestinationExchange, Key: "cron.*"},
+			{Source: logsTopicExchange, Destination: "team-ops", DestinationType: topology.DestinationExchange, Key: "sshd.*"},
+			{Source: "team-web", Destination: "team-web-errors", Key: "nginx.error"},
+			{Source: "team-web", Destination: "team-web-warnings", Key: "nginx.warn"},
+			{Source: "team-ops", Destination: "team-ops-errors", Key: "cron.error"},
+			{Source: "team-ops", Destination: "team-ops-errors", Key: "sshd.error"},
+			{Source: "team-unrouted", Destination: "team-unrouted"},
+		},
+	}
+	err := t.Validate()
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
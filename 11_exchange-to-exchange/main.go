@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"go-rabbit/topology"
+)
+
+const (
+	helpMode        = "choose between 'declare', 'route', 'bind', 'unbind', 'publish' or 'subscriber'"
+	helpTopology    = "JSON file with the topology (the demo topology if empty)"
+	helpExchange    = "exchange the message is published to"
+	helpKey         = "routing key of the message, or binding key of the exchange binding"
+	helpHeaders     = "comma separated headers of the message, e.g. 'severity=error,code=503' (integers and booleans are typed, quote them to send strings)"
+	helpBody        = "body of the published message"
+	helpSource      = "source exchange of the exchange binding"
+	helpDestination = "destination exchange of the exchange binding"
+	helpQueue       = "queue to consume"
+)
+
+func main() {
+	mode := flag.String("mode", "", helpMode)
+	topologyPath := flag.String("topology", "", helpTopology)
+	exchange := flag.String("exchange", logsTopicExchange, helpExchange)
+	key := flag.String("key", "", helpKey)
+	headers := flag.String("headers", "", helpHeaders)
+	body := flag.String("body", "test log", helpBody)
+	source := flag.String("source", "", helpSource)
+	destination := flag.String("destination", "", helpDestination)
+	queue := flag.String("queue", "", helpQueue)
+	flag.Parse()
+
+	t := demoTopology()
+	if *topologyPath != "" {
+		var err error
+		t, err = topology.Load(*topologyPath)
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+	}
+
+	switch *mode {
+	case "declare":
+		declare(t)
+	case "route":
+		h, err := topology.ParseHeaders(*headers)
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+		route, err := t.Route(*exchange, *key, h)
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+		route.Print(os.Stdout)
+	case "bind", "unbind":
+		if *source == "" || *destination == "" {
+			log.Fatalf("missing source or destination exchange")
+		}
+		bind(*mode == "bind", *source, *destination, *key)
+	case "publish":
+		h, err := topology.ParseHeaders(*headers)
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+		publish(*exchange, *key, h, *body)
+	case "subscriber":
+		if *queue == "" {
+			log.Fatalf("missing queue")
+		}
+		subscriber(*queue)
+	default:
+		log.Fatalf(helpMode)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"go-rabbit/topology"
+)
+
+// A topology with the exchanges of the examples, and some queues bound
+// like the example subscribers do:
+//
+//   - logs (fanout, example 3): the 'archive' subscription;
+//   - logs-routing (direct, example 4): subscribers for the errors and
+//     for warn and above, with the 'logs-unroutable' alternate exchange;
+//   - logs-topic-exchange (topic, example 5): subscribers for 'nginx.*',
+//     '*.error' and 'cron.info', and the team exchanges of example 11;
+//   - logs-headers (headers, example 10): subscribers for the eu errors
+//     (all) and for the acme tenant or the cron facility (any).
+func examplesTopology() *topology.Topology {
+	t := &topology.Topology{
+		Exchanges: []topology.Exchange{
+			{Name: "logs", Type: amqp.ExchangeFanout},
+			{Name: "logs-routing", Type: amqp.ExchangeDirect, AlternateExchange: "logs-unroutable"},
+			{Name: "logs-unroutable", Type: amqp.ExchangeFanout},
+			{Name: "logs-topic-exchange", Type: amqp.ExchangeTopic},
+			{Name: "team-web", Type: amqp.ExchangeDirect},
+			{Name: "logs-headers", Type: amqp.ExchangeHeaders},
+		},
+		Queues: []topology.Queue{
+			{Name: "logs.subscription.archive"},
+			{Name: "errors"},
+			{Name: "warn-and-above"},
+			{Name: "logs-unroutable"},
+			{Name: "nginx-all"},
+			{Name: "all-errors"},
+			{Name: "cron-info"},
+			{Name: "team-web-errors"},
+			{Name: "eu-errors"},
+			{Name: "acme-or-cron"},
+		},
+		Bindings: []topology.Binding{
+			{Source: "logs", Destination: "logs.subscription.archive"},
+			{Source: "logs-routing", Destination: "errors", Key: "error"},
+			{Source: "logs-routing", Destination: "warn-and-above", Key: "warn"},
+			{Source: "logs-routing", Destination: "warn-and-above", Key: "error"},
+			{Source: "logs-routing", Destination: "warn-and-above", Key: "fatal"},
+			{Source: "logs-unroutable", Destination: "logs-unroutable"},
+			{Source: "logs-topic-exchange", Destination: "nginx-all", Key: "nginx.*"},
+			{Source: "logs-topic-exchange", Destination: "all-errors", Key: "*.error"},
+			{Source: "logs-topic-exchange", Destination: "cron-info", Key: "cron.info"},
+			{Source: "logs-topic-exchange", Destination: "team-web", DestinationType: topology.DestinationExchange, Key: "nginx.#"},
+			{Source: "team-web", Destination: "team-web-errors", Key: "nginx.error"},
+			{Source: "logs-headers", Destination: "eu-errors", Arguments: amqp.Table{"x-match": "all", "severity": "error", "region": "eu"}},
+			{Source: "logs-headers", Destination: "acme-or-cron", Arguments: amqp.Table{"x-match": "any", "tenant": "acme", "facility": "cron"}},
+		},
+	}
+	err := t.Validate()
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
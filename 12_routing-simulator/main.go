@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"go-rabbit/topology"
+)
+
+const (
+	helpTopology = "JSON file with the topology (the topology of the examples if empty)"
+	helpCases    = "JSON file with the messages to route"
+	helpExchange = "exchange of the message to route (without a cases file)"
+	helpKey      = "routing key of the message to route (without a cases file)"
+	helpHeaders  = "comma separated headers of the message to route, e.g. 'severity=error,code=503' (integers and booleans are typed, quote them for strings)"
+	helpVerbose  = "print the route of each message through the exchanges"
+)
+
+func main() {
+	topologyPath := flag.String("topology", "", helpTopology)
+	casesPath := flag.String("cases", "", helpCases)
+	exchange := flag.String("exchange", "", helpExchange)
+	key := flag.String("key", "", helpKey)
+	headers := flag.String("headers", "", helpHeaders)
+	verbose := flag.Bool("verbose", false, helpVerbose)
+	flag.Parse()
+
+	t := examplesTopology()
+	if *topologyPath != "" {
+		var err error
+		t, err = topology.Load(*topologyPath)
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+	}
+
+	var cases []routingCase
+	if *casesPath != "" {
+		var err error
+		cases, err = loadCases(*casesPath)
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+	} else {
+		if *exchange == "" {
+			log.Fatalf("missing exchange or cases file")
+		}
+		h, err := topology.ParseHeaders(*headers)
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+		cases = []routingCase{{Exchange: *exchange, Key: *key, Headers: h}}
+	}
+
+	if !simulate(os.Stdout, t, cases, *verbose) {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"go-rabbit/topology"
+)
+
+// A message to route: the exchange it's published to, with its routing
+// key and headers. The cases file is a JSON array of them, e.g.:
+//
+//	[
+//		{ "exchange": "logs-topic-exchange", "key": "nginx.error" },
+//		{ "exchange": "logs-headers", "headers": { "severity": "error", "region": "eu" } }
+//	]
+type routingCase struct {
+	Exchange string     `json:"exchange"`
+	Key      string     `json:"key"`
+	Headers  amqp.Table `json:"headers,omitempty"`
+}
+
+func loadCases(path string) ([]routingCase, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cases []routingCase
+	err = json.Unmarshal(data, &cases)
+	if err != nil {
+		return nil, err
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("no messages in '%s'", path)
+	}
+	return cases, nil
+}
+
+// Route the messages through the topology, printing the destination queues
+// of each one (and the route, if verbose). Returns false if a message can't
+// be routed at all (e.g. its exchange doesn't exist).
+func simulate(w io.Writer, t *topology.Topology, cases []routingCase, verbose bool) bool {
+	routed, unroutable, failed := 0, 0, 0
+	for _, c := range cases {
+		route, err := t.Route(c.Exchange, c.Key, c.Headers)
+		if err != nil {
+			fmt.Fprintf(w, "%s: error: %s\n", describe(c), err)
+			failed++
+			continue
+		}
+		if route.Unroutable() {
+			fmt.Fprintf(w, "%s: unroutable\n", describe(c))
+			unroutable++
+		} else {
+			fmt.Fprintf(w, "%s: %s\n", describe(c), strings.Join(route.Queues, ", "))
+			routed++
+		}
+		if verbose {
+			route.Print(w)
+			fmt.Fprintln(w)
+		}
+	}
+	fmt.Fprintf(w, "%d messages: %d routed, %d unroutable, %d failed\n", len(cases), routed, unroutable, failed)
+	return failed == 0
+}
+
+func describe(c routingCase) string {
+	s := fmt.Sprintf("'%s' key '%s'", c.Exchange, c.Key)
+	if len(c.Headers) > 0 {
+		s += fmt.Sprintf(" headers %v", map[string]interface{}(c.Headers))
+	}
+	return s
+}
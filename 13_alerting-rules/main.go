@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"log"
+)
+
+const (
+	helpMode  = "choose between 'engine' or 'alerts'"
+	helpRules = "JSON file with the alerting rules (reloaded on SIGHUP)"
+	helpBind  = "binding key of the alerts, in the format '<severity>.<rule>'"
+
+	logsTopicExchange  = "logs-topic-exchange"
+	logsAlertsExchange = "logs-alerts"
+)
+
+func main() {
+	mode := flag.String("mode", "", helpMode)
+	rules := flag.String("rules", "", helpRules)
+	bind := flag.String("bind", "#", helpBind)
+	flag.Parse()
+
+	switch *mode {
+	case "engine":
+		if *rules == "" {
+			log.Fatalf("missing rules file")
+		}
+		engine(*rules)
+	case "alerts":
+		alerts(*bind)
+	default:
+		log.Fatalf(helpMode)
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"go-rabbit/topic"
+)
+
+// The rules file, e.g.:
+//
+//	{
+//		"rules": [
+//			{ "name": "sshd-errors", "key": "sshd.#.error", "more_than": 10, "window": "1m", "severity": "critical" },
+//			{ "name": "disk-full", "key": "#", "body": "(?i)no space left", "suppress": "10m" }
+//		]
+//	}
+//
+// A rule matches the logs with a routing key matching its key (a topic
+// pattern, '#' if empty) and, if provided, a body matching its regular
+// expression. The rule fires when more than 'more_than' logs (0 if empty)
+// match within the window: without a window, each matching log fires it.
+// After firing, the alerts of the rule are suppressed for 'suppress' (the
+// window if empty): suppressed alerts are counted in the next alert. The
+// alerts are published with the routing key '<severity>.<rule name>', so
+// names and severities can't contain dots or wildcards.
+type rulesConfig struct {
+	Rules []ruleConfig `json:"rules"`
+}
+
+type ruleConfig struct {
+	Name     string `json:"name"`
+	Key      string `json:"key"`
+	Body     string `json:"body,omitempty"`
+	MoreThan int    `json:"more_than,omitempty"`
+	Window   string `json:"window,omitempty"`
+	Suppress string `json:"suppress,omitempty"`
+	Severity string `json:"severity,omitempty"`
+}
+
+// The window of a rule slides by a fraction of the window: the matching
+// logs are counted per slide, so the state of a rule doesn't grow with the
+// number of logs. The first slide can be partially out of the window, so
+// the count can include the logs of up to a slide earlier.
+const windowSlides = 60
+
+// The count of the matching logs of a slide, starting at the provided time.
+type slideCount struct {
+	start time.Time
+	count int
+}
+
+// A compiled rule, with its state: the counts of the matching logs in the
+// window, and the suppression of the repeated alerts.
+type rule struct {
+	config   ruleConfig
+	key      *topic.Pattern
+	body     *regexp.Regexp
+	window   time.Duration
+	suppress time.Duration
+
+	slides     []slideCount
+	until      time.Time // alerts are suppressed until this time
+	suppressed int
+}
+
+// Load and compile the rules of the provided file.
+func loadRules(path string) ([]*rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg rulesConfig
+	err = json.Unmarshal(data, &cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Rules) == 0 {
+		return nil, fmt.Errorf("no rules in '%s'", path)
+	}
+
+	var rules []*rule
+	names := map[string]bool{}
+	for _, c := range cfg.Rules {
+		if c.Name == "" || names[c.Name] {
+			return nil, fmt.Errorf("missing or duplicated rule name: '%s'", c.Name)
+		}
+		if strings.ContainsAny(c.Name, ".*#") || strings.ContainsAny(c.Severity, ".*#") {
+			return nil, fmt.Errorf("dots or wildcards in name or severity of rule '%s'", c.Name)
+		}
+		names[c.Name] = true
+		r, err := compileRule(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule '%s': %s", c.Name, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func compileRule(c ruleConfig) (*rule, error) {
+	if c.Key == "" {
+		c.Key = topic.Hash
+	}
+	if c.Severity == "" {
+		c.Severity = "warning"
+	}
+	if c.MoreThan < 0 {
+		return nil, fmt.Errorf("invalid more_than: %d", c.MoreThan)
+	}
+
+	// The keys of the rules are typed by users: reject the patterns that
+	// are most likely a mistake, even if the broker would accept them.
+	err := topic.ValidatePattern(c.Key)
+	if err != nil {
+		return nil, err
+	}
+	r := &rule{config: c}
+	r.key, err = topic.Compile(c.Key)
+	if err != nil {
+		return nil, err
+	}
+	if c.Body != "" {
+		r.body, err = regexp.Compile(c.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.Window != "" {
+		r.window, err = time.ParseDuration(c.Window)
+		if err != nil || r.window <= 0 {
+			return nil, fmt.Errorf("invalid window: '%s'", c.Window)
+		}
+	}
+	if c.MoreThan > 0 && r.window == 0 {
+		return nil, fmt.Errorf("more_than without a window")
+	}
+	r.suppress = r.window
+	if c.Suppress != "" {
+		r.suppress, err = time.ParseDuration(c.Suppress)
+		if err != nil || r.suppress < 0 {
+			return nil, fmt.Errorf("invalid suppress: '%s'", c.Suppress)
+		}
+	}
+	return r, nil
+}
+
+// Keep the state of the old rules that are unchanged in the new ones, so
+// a reload doesn't reset their windows and their suppression.
+func carryState(old, rules []*rule) {
+	for _, r := range rules {
+		for _, o := range old {
+			if o.config == r.config {
+				r.slides, r.until, r.suppressed = o.slides, o.until, o.suppressed
+				break
+			}
+		}
+	}
+}
+
+// An alert, published as JSON to the alerts exchange.
+type alert struct {
+	Rule       string    `json:"rule"`
+	Severity   string    `json:"severity"`
+	Time       time.Time `json:"time"`
+	Count      int       `json:"count"`
+	Window     string    `json:"window,omitempty"`
+	Suppressed int       `json:"suppressed,omitempty"`
+	RoutingKey string    `json:"routing_key"`
+	Body       string    `json:"body"`
+}
+
+func (a alert) String() string {
+	s := fmt.Sprintf("[%s] %s: %d logs", a.Severity, a.Rule, a.Count)
+	if a.Window != "" {
+		s += " in " + a.Window
+	}
+	if a.Suppressed > 0 {
+		s += fmt.Sprintf(" (%d alerts suppressed)", a.Suppressed)
+	}
+	return s + fmt.Sprintf(", last %s: %s", a.RoutingKey, a.Body)
+}
+
+// Evaluate the rule on a log received at the provided time, returning the
+// alert if the rule fires (and it's not suppressed).
+func (r *rule) evaluate(routingKey string, body []byte, now time.Time) (alert, bool) {
+	if !r.key.Match(routingKey) || (r.body != nil && !r.body.Match(body)) {
+		return alert{}, false
+	}
+
+	count := r.count(now)
+	if count <= r.config.MoreThan {
+		return alert{}, false
+	}
+
+	if now.Before(r.until) {
+		r.suppressed++
+		return alert{}, false
+	}
+	a := alert{
+		Rule:       r.config.Name,
+		Severity:   r.config.Severity,
+		Time:       now,
+		Count:      count,
+		Window:     r.config.Window,
+		Suppressed: r.suppressed,
+		RoutingKey: routingKey,
+		Body:       string(body),
+	}
+	r.until = now.Add(r.suppress)
+	r.suppressed = 0
+	return a, true
+}
+
+// Count a matching log received at the provided time, returning the number
+// of matching logs in the window (just this one, without a window).
+func (r *rule) count(now time.Time) int {
+	if r.window == 0 {
+		return 1
+	}
+	slide := r.window / windowSlides
+	if slide <= 0 {
+		slide = 1
+	}
+	start := now.Truncate(slide)
+	if last := len(r.slides) - 1; last >= 0 && r.slides[last].start.Equal(start) {
+		r.slides[last].count++
+	} else {
+		r.slides = append(r.slides, slideCount{start: start, count: 1})
+	}
+
+	// Drop the slides ending before the window, then sum the others.
+	first := 0
+	for !r.slides[first].start.Add(slide).After(now.Add(-r.window)) {
+		first++
+	}
+	r.slides = r.slides[first:]
+	count := 0
+	for _, s := range r.slides {
+		count += s.count
+	}
+	return count
+}
@@ -0,0 +1,266 @@
+// Package filter implements the content filters of the subscribers: the
+// broker routes by exchange and routing key (or headers) only, while the
+// filters discard the received messages by body or headers, after the
+// delivery.
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// How often the counts of the passed and filtered messages are printed,
+// if changed.
+const ReportInterval = 10 * time.Second
+
+// The comparison operators of the field predicates, longest first.
+var operators = []string{"!=", ">=", "<=", "=", ">", "<"}
+
+// A filter of the received messages. The filter expressions are:
+//
+//	contains:<text>                 the body contains the text
+//	regex:<expression>              the body matches the regular expression
+//	json:<field><op><value>         a field of the JSON body, e.g. 'json:status>=500'
+//	header:<name><op><value>        a header of the message, e.g. 'header:host=web1'
+//
+// The operators are =, !=, >, >=, < and <=: numbers are compared as numbers,
+// other values as strings (only for = and !=). Nested JSON fields are joined
+// by dots, e.g. 'json:user.name=bob'. Without the operator, the JSON field
+// or the header must be present. A leading '!' negates the filter.
+type Filter struct {
+	expr  string
+	match func(message amqp.Delivery) bool
+}
+
+func Parse(expr string) (Filter, error) {
+	f := Filter{expr: expr}
+	negate := strings.HasPrefix(expr, "!")
+	kind, arg, ok := cut(strings.TrimPrefix(expr, "!"), ":")
+	if !ok {
+		return f, fmt.Errorf("invalid filter: '%s'", expr)
+	}
+
+	switch kind {
+	case "contains":
+		f.match = func(message amqp.Delivery) bool {
+			return strings.Contains(string(message.Body), arg)
+		}
+	case "regex":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return f, fmt.Errorf("invalid filter '%s': %s", expr, err)
+		}
+		f.match = func(message amqp.Delivery) bool {
+			return re.Match(message.Body)
+		}
+	case "json":
+		field, op, value := parsePredicate(arg)
+		if field == "" {
+			return f, fmt.Errorf("invalid filter: '%s'", expr)
+		}
+		path := strings.Split(field, ".")
+		f.match = func(message amqp.Delivery) bool {
+			actual, ok := jsonField(message.Body, path)
+			return ok && (op == "" || compare(actual, op, value))
+		}
+	case "header":
+		name, op, value := parsePredicate(arg)
+		if name == "" {
+			return f, fmt.Errorf("invalid filter: '%s'", expr)
+		}
+		f.match = func(message amqp.Delivery) bool {
+			actual, ok := message.Headers[name]
+			return ok && (op == "" || compare(actual, op, value))
+		}
+	default:
+		return f, fmt.Errorf("invalid filter: '%s'", expr)
+	}
+
+	if negate {
+		match := f.match
+		f.match = func(message amqp.Delivery) bool {
+			return !match(message)
+		}
+	}
+	return f, nil
+}
+
+// Split the string around the first separator.
+func cut(s, sep string) (string, string, bool) {
+	i := strings.Index(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
+
+// Split a predicate '<field><op><value>' at its first operator. The
+// operator is empty if there isn't one.
+func parsePredicate(s string) (field, op, value string) {
+	i := strings.IndexAny(s, "!=<>")
+	if i < 0 {
+		return s, "", ""
+	}
+	for _, op := range operators {
+		if strings.HasPrefix(s[i:], op) {
+			return s[:i], op, s[i+len(op):]
+		}
+	}
+	return "", "", ""
+}
+
+// The value of a field of a JSON body, following the path.
+func jsonField(body []byte, path []string) (interface{}, bool) {
+	var value interface{}
+	if json.Unmarshal(body, &value) != nil {
+		return nil, false
+	}
+	for _, name := range path {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		value, ok = object[name]
+		if !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// Compare a JSON value or a header with the value of a predicate. Byte
+// array headers (the AMQP 'x' field type) are compared as strings.
+func compare(actual interface{}, op, value string) bool {
+	if b, ok := actual.([]byte); ok {
+		actual = string(b)
+	}
+	if a, ok := number(actual); ok {
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			switch op {
+			case "=":
+				return a == v
+			case "!=":
+				return a != v
+			case ">":
+				return a > v
+			case ">=":
+				return a >= v
+			case "<":
+				return a < v
+			case "<=":
+				return a <= v
+			}
+		}
+	}
+	switch op {
+	case "=":
+		return fmt.Sprint(actual) == value
+	case "!=":
+		return fmt.Sprint(actual) != value
+	}
+	return false
+}
+
+// The numeric value of JSON numbers and of the numeric header types.
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// Whether the message matches the filter.
+func (f Filter) Match(message amqp.Delivery) bool {
+	return f.match(message)
+}
+
+func (f Filter) String() string {
+	return f.expr
+}
+
+// The filters of a subscriber, set with a repeatable flag (it implements
+// flag.Value): a message is passed only if it matches all of them. The
+// passed and the filtered messages are counted.
+type Set struct {
+	filters []Filter
+
+	mu       sync.Mutex
+	passed   int
+	filtered int
+}
+
+func (s *Set) String() string {
+	exprs := make([]string, len(s.filters))
+	for i, f := range s.filters {
+		exprs[i] = f.expr
+	}
+	return strings.Join(exprs, ",")
+}
+
+func (s *Set) Set(expr string) error {
+	f, err := Parse(expr)
+	if err != nil {
+		return err
+	}
+	s.filters = append(s.filters, f)
+	return nil
+}
+
+func (s *Set) Enabled() bool {
+	return len(s.filters) > 0
+}
+
+// Whether the message passes all the filters.
+func (s *Set) Accept(message amqp.Delivery) bool {
+	passed := true
+	for _, f := range s.filters {
+		if !f.match(message) {
+			passed = false
+			break
+		}
+	}
+	s.mu.Lock()
+	if passed {
+		s.passed++
+	} else {
+		s.filtered++
+	}
+	s.mu.Unlock()
+	return passed
+}
+
+// Print the counts of the passed and filtered messages periodically, if
+// changed.
+func (s *Set) Report() {
+	printed := ""
+	for range time.Tick(ReportInterval) {
+		s.mu.Lock()
+		stats := fmt.Sprintf("%d passed, %d filtered", s.passed, s.filtered)
+		s.mu.Unlock()
+		if stats != printed {
+			log.Printf("Filtered messages: %s", stats)
+			printed = stats
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package filter
+
+import (
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestMatch(t *testing.T) {
+	message := amqp.Delivery{
+		Body: []byte(`{"status": 503, "user": {"name": "bob"}, "msg": "disk full"}`),
+		Headers: amqp.Table{
+			"host":    "web1",
+			"retries": int32(3),
+			"trace":   []byte("abc"),
+		},
+	}
+	tests := []struct {
+		expr  string
+		match bool
+	}{
+		{"contains:disk", true},
+		{"!contains:disk", false},
+		{`regex:"status": 5\d\d`, true},
+		{"json:status>=500", true},
+		{"json:status<500", false},
+		{"json:status=503", true},
+		{"json:user.name=bob", true},
+		{"json:user.name!=bob", false},
+		{"json:user", true},
+		{"json:missing", false},
+		{"json:msg>a", false},
+		{"header:host=web1", true},
+		{"header:host!=web1", false},
+		{"header:retries>2", true},
+		{"header:retries", true},
+		{"!header:missing", true},
+		{"header:trace=abc", true},
+	}
+	for _, test := range tests {
+		f, err := Parse(test.expr)
+		if err != nil {
+			t.Errorf("Parse('%s'): %s", test.expr, err)
+			continue
+		}
+		if got := f.Match(message); got != test.match {
+			t.Errorf("Match('%s') = %t, want %t", test.expr, got, test.match)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, expr := range []string{"", "disk", "json:", "json:=3", "header:", "unknown:x", "regex:("} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse('%s') accepted", expr)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	s := &Set{}
+	for _, expr := range []string{"contains:error", "!contains:health"} {
+		if err := s.Set(expr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for body, passed := range map[string]bool{"error at /api": true, "error at /health": false, "info": false} {
+		if got := s.Accept(amqp.Delivery{Body: []byte(body)}); got != passed {
+			t.Errorf("Accept('%s') = %t, want %t", body, got, passed)
+		}
+	}
+	if s.passed != 1 || s.filtered != 2 {
+		t.Errorf("%d passed and %d filtered, want 1 and 2", s.passed, s.filtered)
+	}
+}
@@ -0,0 +1,94 @@
+// Package overflow bounds the subscriber queues, so that a slow subscriber
+// doesn't make its queue grow without limit, counts the messages dropped
+// by the bounded queues, and retries the messages rejected by full queues
+// on the publisher side.
+package overflow
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// The overflow behaviours of a queue that reached its max length.
+const (
+	DropHead         = "drop-head"
+	RejectPublish    = "reject-publish"
+	RejectPublishDLX = "reject-publish-dlx"
+)
+
+const (
+	helpMaxLength  = "max number of logs in the subscriber queue (0 for no limit)"
+	helpMaxBytes   = "max size in bytes of the subscriber queue (0 for no limit)"
+	helpMessageTTL = "time after which logs in the subscriber queue are dropped (0 for no limit)"
+	helpOverflow   = "overflow behaviour: 'drop-head', 'reject-publish' or 'reject-publish-dlx'"
+)
+
+// The bounds of a subscriber queue. Zero values mean no limit. When the
+// queue is full the overflow behaviour applies: the oldest messages are
+// dropped (drop-head, the default) or the new ones are rejected
+// (reject-publish, reject-publish-dlx). Messages older than the TTL are
+// dropped too.
+type Bounds struct {
+	MaxLength      int
+	MaxLengthBytes int
+	MessageTTL     time.Duration
+	Overflow       string
+}
+
+// Register the command line flags of the bounds.
+func (b *Bounds) RegisterFlags(flags *flag.FlagSet) {
+	flags.IntVar(&b.MaxLength, "max-length", 0, helpMaxLength)
+	flags.IntVar(&b.MaxLengthBytes, "max-length-bytes", 0, helpMaxBytes)
+	flags.DurationVar(&b.MessageTTL, "message-ttl", 0, helpMessageTTL)
+	flags.StringVar(&b.Overflow, "overflow", "", helpOverflow)
+}
+
+func (b Bounds) Validate() error {
+	if b.MaxLength < 0 || b.MaxLengthBytes < 0 || b.MessageTTL < 0 {
+		return fmt.Errorf("invalid queue bounds")
+	}
+	switch b.Overflow {
+	case "":
+	case DropHead, RejectPublish, RejectPublishDLX:
+		if b.MaxLength == 0 && b.MaxLengthBytes == 0 {
+			return fmt.Errorf("overflow behaviour without max length")
+		}
+	default:
+		return fmt.Errorf("invalid overflow behaviour: '%s'", b.Overflow)
+	}
+	return nil
+}
+
+func (b Bounds) Enabled() bool {
+	return b.MaxLength > 0 || b.MaxLengthBytes > 0 || b.MessageTTL > 0
+}
+
+// The queue arguments implementing the bounds. Dropped messages (and the
+// rejected ones with reject-publish-dlx) are dead-lettered: they are
+// republished through the default exchange to the provided drops queue,
+// so that the subscriber can count them.
+func (b Bounds) Arguments(dropsQueue string) amqp.Table {
+	if !b.Enabled() {
+		return nil
+	}
+	args := amqp.Table{
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": dropsQueue,
+	}
+	if b.MaxLength > 0 {
+		args["x-max-length"] = int64(b.MaxLength)
+	}
+	if b.MaxLengthBytes > 0 {
+		args["x-max-length-bytes"] = int64(b.MaxLengthBytes)
+	}
+	if b.MessageTTL > 0 {
+		args["x-message-ttl"] = b.MessageTTL.Milliseconds()
+	}
+	if b.Overflow != "" {
+		args["x-overflow"] = b.Overflow
+	}
+	return args
+}
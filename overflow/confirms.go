@@ -0,0 +1,152 @@
+package overflow
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// The retries of the messages nacked by the broker.
+const (
+	RetryAttempts = 3
+	RetryDelay    = time.Second
+)
+
+// The counts of the confirms received by a publisher. Retried messages
+// are counted again when they are confirmed, and the dropped ones are the
+// messages nacked at the last attempt (or failed to be published again).
+type Stats struct {
+	Acked   int
+	Nacked  int
+	Retried int
+	Dropped int
+}
+
+// A publisher that, with confirms, reacts to the messages nacked by the
+// broker. The broker nacks a message when a bound queue with the
+// reject-publish (or reject-publish-dlx) overflow behaviour is full: the
+// message is published again after a delay, since the subscriber could
+// catch up in the meantime, up to the max attempts.
+//
+// Delivery tags start from 1 and follow the publishing order, so all the
+// messages of the channel must be published with the publisher: publishing
+// is serialized, to keep the tags of the pending messages right.
+type Publisher struct {
+	channel *amqp.Channel
+	confirm bool
+
+	mu       sync.Mutex
+	tag      uint64
+	pending  map[uint64]publishing
+	retrying int
+	stats    Stats
+}
+
+type publishing struct {
+	exchange  string
+	key       string
+	mandatory bool
+	msg       amqp.Publishing
+	attempt   int
+}
+
+// Create a publisher on the channel, enabling the confirms if requested.
+func NewPublisher(channel *amqp.Channel, confirm bool) (*Publisher, error) {
+	p := &Publisher{channel: channel, confirm: confirm, pending: map[uint64]publishing{}}
+	if !confirm {
+		return p, nil
+	}
+	err := channel.Confirm(false)
+	if err != nil {
+		return nil, err
+	}
+	go p.handleConfirms(channel.NotifyPublish(make(chan amqp.Confirmation, 100)))
+	return p, nil
+}
+
+func (p *Publisher) Publish(exchange, key string, mandatory bool, msg amqp.Publishing) error {
+	return p.publish(publishing{exchange: exchange, key: key, mandatory: mandatory, msg: msg, attempt: 1})
+}
+
+func (p *Publisher) publish(pb publishing) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	err := p.channel.Publish(pb.exchange, pb.key, pb.mandatory, false, pb.msg)
+	if err != nil || !p.confirm {
+		return err
+	}
+	p.tag++
+	p.pending[p.tag] = pb
+	return nil
+}
+
+func (p *Publisher) handleConfirms(confirmations <-chan amqp.Confirmation) {
+	for cnf := range confirmations {
+		p.mu.Lock()
+		pb := p.pending[cnf.DeliveryTag]
+		delete(p.pending, cnf.DeliveryTag)
+		if cnf.Ack {
+			p.stats.Acked++
+			p.mu.Unlock()
+			continue
+		}
+		p.stats.Nacked++
+		retry := pb.attempt > 0 && pb.attempt < RetryAttempts
+		if retry {
+			p.stats.Retried++
+			p.retrying++
+		} else {
+			p.stats.Dropped++
+		}
+		stats := p.stats
+		p.mu.Unlock()
+
+		if !retry {
+			log.Printf("Publish #%d rejected by the broker, dropped after %d attempts (%+v)", cnf.DeliveryTag, pb.attempt, stats)
+			continue
+		}
+		log.Printf("Publish #%d rejected by the broker, retrying in %s (%+v)", cnf.DeliveryTag, RetryDelay, stats)
+		pb.attempt++
+		go p.retry(pb)
+	}
+}
+
+func (p *Publisher) retry(pb publishing) {
+	time.Sleep(RetryDelay)
+	err := p.publish(pb)
+	p.mu.Lock()
+	p.retrying--
+	if err != nil {
+		p.stats.Dropped++
+	}
+	p.mu.Unlock()
+	if err != nil {
+		log.Printf("Retry of a rejected message failed: %s", err)
+	}
+}
+
+func (p *Publisher) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
+
+// Wait until all the published messages are confirmed (and the nacked
+// ones retried), up to the timeout. Returns false on timeout.
+func (p *Publisher) Wait(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		p.mu.Lock()
+		done := len(p.pending) == 0 && p.retrying == 0
+		p.mu.Unlock()
+		if done {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
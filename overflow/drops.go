@@ -0,0 +1,79 @@
+package overflow
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// How often the drop statistics are printed, if changed.
+const StatsInterval = 10 * time.Second
+
+// Declare the queue collecting the messages dropped from the subscriber
+// queue, and count them by reason ('maxlen' for overflows, 'expired' for
+// the TTL), as reported by the broker in the x-death header. The queue is
+// exclusive, unless a name is provided: since the queue arguments of a
+// durable subscriber queue can't change, its drops queue must keep the
+// same name. Note that with reject-publish the rejected messages never
+// enter the queue, so they can't be counted here: the publisher is
+// notified instead (see Publisher).
+func WatchDrops(channel *amqp.Channel, name string) (string, error) {
+	var queue amqp.Queue
+	var err error
+	if name == "" {
+		queue, err = channel.QueueDeclare("", false, true, true, false, nil)
+	} else {
+		queue, err = channel.QueueDeclare(name, true, false, false, false, nil)
+	}
+	if err != nil {
+		return "", err
+	}
+	drops, err := channel.Consume(queue.Name, "", true, false, false, false, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var mu sync.Mutex
+	counts := map[string]int{}
+	go func() {
+		for drop := range drops {
+			mu.Lock()
+			counts[deathReason(drop)]++
+			mu.Unlock()
+		}
+	}()
+
+	go func() {
+		printed := ""
+		for range time.Tick(StatsInterval) {
+			mu.Lock()
+			stats := fmt.Sprintf("%v", counts)
+			mu.Unlock()
+			if stats != printed {
+				log.Printf("Dropped messages by reason: %s", stats)
+				printed = stats
+			}
+		}
+	}()
+
+	return queue.Name, nil
+}
+
+func deathReason(message amqp.Delivery) string {
+	deaths, ok := message.Headers["x-death"].([]interface{})
+	if !ok || len(deaths) == 0 {
+		return "unknown"
+	}
+	death, ok := deaths[0].(amqp.Table)
+	if !ok {
+		return "unknown"
+	}
+	reason, ok := death["reason"].(string)
+	if !ok {
+		return "unknown"
+	}
+	return reason
+}
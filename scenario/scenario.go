@@ -0,0 +1,133 @@
+// Package scenario replays the logs of a scenario file at their times, on
+// the real clock or on an accelerated one, so that publishers can publish
+// the same logs at every run.
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// A clock, so that the publisher can run on the real time or on an
+// accelerated time.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// The real clock, accelerated by the speed factor: sleeps are shortened,
+// and the time flows faster since the start.
+type ScaledClock struct {
+	start time.Time
+	speed float64
+}
+
+func NewScaledClock(speed float64) ScaledClock {
+	return ScaledClock{start: time.Now(), speed: speed}
+}
+
+func (c ScaledClock) Now() time.Time {
+	return c.start.Add(time.Duration(float64(time.Since(c.start)) * c.speed))
+}
+
+func (c ScaledClock) Sleep(d time.Duration) {
+	time.Sleep(time.Duration(float64(d) / c.speed))
+}
+
+// A log of a scenario, published at a time since the scenario start.
+type Event struct {
+	Offset time.Duration
+	Key    string
+	Body   string
+}
+
+// Load a scenario from a JSON file, containing the logs to publish with
+// their routing key and body. The times of the logs are either all offsets
+// from the scenario start, or all timestamps (RFC 3339): the earliest log
+// is then the start. E.g.:
+//
+//	[
+//		{ "at": "0s", "key": "nginx.error", "body": "upstream timed out" },
+//		{ "at": "1.5s", "key": "cron.info", "body": "backup completed" }
+//	]
+//
+// A scenario without logs is an error.
+func Load(path string) ([]Event, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []struct {
+		At   string `json:"at"`
+		Key  string `json:"key"`
+		Body string `json:"body"`
+	}
+	err = json.Unmarshal(data, &entries)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no logs in scenario '%s'", path)
+	}
+
+	// The format of the first log is the format of the whole scenario: the
+	// offsets from a timestamp are meaningless with other offsets.
+	events := make([]Event, len(entries))
+	times := make([]time.Time, len(entries))
+	timestamps := false
+	for i, e := range entries {
+		t, err := time.Parse(time.RFC3339, e.At)
+		if i == 0 {
+			timestamps = err == nil
+		}
+		if (err == nil) != timestamps {
+			return nil, fmt.Errorf("scenario mixing offsets and timestamps: '%s'", e.At)
+		}
+		events[i] = Event{Key: e.Key, Body: e.Body}
+		if timestamps {
+			times[i] = t
+			continue
+		}
+		events[i].Offset, err = time.ParseDuration(e.At)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time of scenario log: '%s'", e.At)
+		}
+		if events[i].Offset < 0 {
+			return nil, fmt.Errorf("scenario log before the start: '%s'", e.At)
+		}
+	}
+
+	// With timestamps, the earliest log is the start.
+	if timestamps {
+		start := times[0]
+		for _, t := range times {
+			if t.Before(start) {
+				start = t
+			}
+		}
+		for i, t := range times {
+			events[i].Offset = t.Sub(start)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Offset < events[j].Offset })
+	return events, nil
+}
+
+// Replay the scenario on the clock, publishing each log at its time.
+func Replay(events []Event, c Clock, publish func(key, body string) error) error {
+	elapsed := time.Duration(0)
+	for _, e := range events {
+		c.Sleep(e.Offset - elapsed)
+		elapsed = e.Offset
+
+		err := publish(e.Key, e.Body)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package scenario
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// A fake clock, where sleeping just moves the time forward.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func writeScenario(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "scenario.json")
+	err := os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReplay(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := &fakeClock{now: start}
+	events := []Event{
+		{Offset: 0, Key: "nginx.error", Body: "a"},
+		{Offset: 1500 * time.Millisecond, Key: "cron.info", Body: "b"},
+		{Offset: 1500 * time.Millisecond, Key: "cron.info", Body: "c"},
+		{Offset: 10 * time.Second, Key: "sshd.warn", Body: "d"},
+	}
+
+	type published struct {
+		at   time.Duration
+		key  string
+		body string
+	}
+	var got []published
+	err := Replay(events, c, func(key, body string) error {
+		got = append(got, published{c.Now().Sub(start), key, body})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []published{
+		{0, "nginx.error", "a"},
+		{1500 * time.Millisecond, "cron.info", "b"},
+		{1500 * time.Millisecond, "cron.info", "c"},
+		{10 * time.Second, "sshd.warn", "d"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("published %v, want %v", got, want)
+	}
+}
+
+func TestReplayError(t *testing.T) {
+	failure := errors.New("publish failed")
+	calls := 0
+	err := Replay([]Event{{Key: "a"}, {Key: "b"}}, &fakeClock{}, func(key, body string) error {
+		calls++
+		return failure
+	})
+	if err != failure || calls != 1 {
+		t.Errorf("error %v after %d calls, want the publish error after 1 call", err, calls)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := writeScenario(t, `[
+		{ "at": "2s", "key": "b", "body": "second" },
+		{ "at": "0s", "key": "a", "body": "first" }
+	]`)
+	events, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Event{{0, "a", "first"}, {2 * time.Second, "b", "second"}}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("loaded %v, want %v", events, want)
+	}
+
+	path = writeScenario(t, `[
+		{ "at": "2024-01-01T10:00:05Z", "key": "b" },
+		{ "at": "2024-01-01T10:00:00Z", "key": "a" }
+	]`)
+	events, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []Event{{0, "a", ""}, {5 * time.Second, "b", ""}}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("loaded %v, want %v", events, want)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	for _, content := range []string{
+		`[]`,
+		`null`,
+		`[{ "at": "soon", "key": "a" }]`,
+		`[{ "at": "-1s", "key": "a" }]`,
+		`[{ "at": "1s", "key": "a" }, { "at": "2024-01-01T10:00:00Z", "key": "b" }]`,
+		`[{ "at": "2024-01-01T10:00:00Z", "key": "a" }, { "at": "1s", "key": "b" }]`,
+	} {
+		if _, err := Load(writeScenario(t, content)); err == nil {
+			t.Errorf("scenario accepted: %s", content)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Package topic validates routing keys and binding patterns of topic
+// exchanges, and matches them with the semantics of RabbitMQ.
+//
+// A routing key is a list of words delimited by dots. The empty key has no
+// words at all. In a binding pattern, the '*' word matches exactly one word
+// and the '#' word matches zero or more words.
+//
+// The broker accepts any key and pattern up to the max length: words can be
+// empty ('a..b' has three words, the second one empty, and 'a.' has two),
+// and '*' and '#' are literal characters inside longer words (e.g. 'a*').
+// Compile and Match follow the broker, while ValidateKey and
+// ValidatePattern are stricter, for the keys and patterns typed by users:
+// they reject what's most likely a mistake, empty words and words mixing
+// '*' or '#' with other characters.
+package topic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The max length in bytes of routing keys and binding keys (AMQP short
+// strings).
+const MaxLength = 255
+
+// Wildcard words of binding patterns.
+const (
+	Star = "*"
+	Hash = "#"
+)
+
+// Split a routing key (or a binding pattern) into its words.
+func Words(key string) []string {
+	if key == "" {
+		return nil
+	}
+	return strings.Split(key, ".")
+}
+
+// Validate a routing key: up to the max length, without empty words and
+// without words mixing '*' or '#' with other characters.
+func ValidateKey(key string) error {
+	if len(key) > MaxLength {
+		return fmt.Errorf("routing key longer than %d bytes", MaxLength)
+	}
+	return validateWords("routing key", key)
+}
+
+// Validate a binding pattern, with the same rules of the routing keys: the
+// wildcards are just words of the pattern.
+func ValidatePattern(pattern string) error {
+	if len(pattern) > MaxLength {
+		return fmt.Errorf("binding pattern longer than %d bytes", MaxLength)
+	}
+	return validateWords("binding pattern", pattern)
+}
+
+func validateWords(kind, s string) error {
+	for _, w := range Words(s) {
+		if w == "" {
+			return fmt.Errorf("empty word in %s '%s'", kind, s)
+		}
+		if w != Star && w != Hash && strings.ContainsAny(w, Star+Hash) {
+			return fmt.Errorf("wildcard inside the word '%s' of %s '%s'", w, kind, s)
+		}
+	}
+	return nil
+}
+
+// A compiled binding pattern. Consecutive '#' words are collapsed, since
+// '#.#' matches the same keys as '#'.
+type Pattern struct {
+	source string
+	words  []string
+}
+
+// Compile any pattern the broker accepts, that is up to the max length:
+// empty words and the words mixing '*' or '#' with other characters are
+// literal words.
+func Compile(pattern string) (*Pattern, error) {
+	if len(pattern) > MaxLength {
+		return nil, fmt.Errorf("binding pattern longer than %d bytes", MaxLength)
+	}
+	p := &Pattern{source: pattern}
+	for _, w := range Words(pattern) {
+		if w == Hash && len(p.words) > 0 && p.words[len(p.words)-1] == Hash {
+			continue
+		}
+		p.words = append(p.words, w)
+	}
+	return p, nil
+}
+
+// Compile the pattern, which must be up to the max length.
+func MustCompile(pattern string) *Pattern {
+	p, err := Compile(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func (p *Pattern) String() string {
+	return p.source
+}
+
+// Whether the pattern has no wildcards, so it only matches itself.
+func (p *Pattern) Literal() bool {
+	for _, w := range p.words {
+		if w == Star || w == Hash {
+			return false
+		}
+	}
+	return true
+}
+
+// Match the routing key, as the broker does: keys with empty words match,
+// while keys longer than the max length never match.
+//
+// Matching is done with dynamic programming over the words of the pattern
+// and of the key, so it takes O(len(pattern) * len(key)) time whatever the
+// number of '#' words (a backtracking matcher is exponential with them).
+func (p *Pattern) Match(key string) bool {
+	if len(key) > MaxLength {
+		return false
+	}
+	words := Words(key)
+
+	// matched[j] tells whether the first i words of the pattern match the
+	// first j words of the key, for the current i.
+	matched := make([]bool, len(words)+1)
+	matched[0] = true
+	for _, pw := range p.words {
+		switch pw {
+		case Hash:
+			// '#' extends any match with zero or more words.
+			for j := 1; j <= len(words); j++ {
+				matched[j] = matched[j] || matched[j-1]
+			}
+		default:
+			// '*' or a literal word consumes exactly one word.
+			for j := len(words); j >= 1; j-- {
+				matched[j] = matched[j-1] && (pw == Star || pw == words[j-1])
+			}
+			matched[0] = false
+		}
+	}
+	return matched[len(words)]
+}
+
+// Match the routing key against the binding pattern. Patterns longer
+// than the max length never match.
+func Match(pattern, key string) bool {
+	p, err := Compile(pattern)
+	if err != nil {
+		return false
+	}
+	return p.Match(key)
+}
@@ -0,0 +1,159 @@
+package topic
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// A simple backtracking matcher, the reference for the matcher of the
+// package.
+func referenceMatch(pattern, key []string) bool {
+	if len(pattern) == 0 {
+		return len(key) == 0
+	}
+	switch pattern[0] {
+	case Hash:
+		for i := 0; i <= len(key); i++ {
+			if referenceMatch(pattern[1:], key[i:]) {
+				return true
+			}
+		}
+		return false
+	case Star:
+		return len(key) > 0 && referenceMatch(pattern[1:], key[1:])
+	default:
+		return len(key) > 0 && key[0] == pattern[0] && referenceMatch(pattern[1:], key[1:])
+	}
+}
+
+// A random pattern and a random key, with few distinct words so that they
+// match often. Both can have empty words and words mixing the wildcards
+// with other characters, which the broker accepts as literal words.
+type patternAndKey struct {
+	pattern string
+	key     string
+}
+
+func randomWords(r *rand.Rand, vocabulary []string, n int) string {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = vocabulary[r.Intn(len(vocabulary))]
+	}
+	return strings.Join(words, ".")
+}
+
+func (patternAndKey) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(patternAndKey{
+		pattern: randomWords(r, []string{"a", "b", "", "a*", Star, Hash}, r.Intn(7)),
+		key:     randomWords(r, []string{"a", "b", "", "a*"}, r.Intn(7)),
+	})
+}
+
+func TestMatchAgainstReference(t *testing.T) {
+	check := func(s patternAndKey) bool {
+		p, err := Compile(s.pattern)
+		if err != nil {
+			t.Logf("pattern '%s': %s", s.pattern, err)
+			return false
+		}
+		return p.Match(s.key) == referenceMatch(Words(s.pattern), Words(s.key))
+	}
+	err := quick.Check(check, &quick.Config{MaxCount: 20000})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	long := strings.Repeat("a.", MaxLength/2) + "a"
+	tests := []struct {
+		pattern string
+		key     string
+		match   bool
+	}{
+		{"#", "", true},
+		{"#", "a", true},
+		{"#", "a.b.c", true},
+		{"#", "..", true},
+		{"#.#", "", true},
+		{"#.#", "a.b", true},
+		{"a.#.b", "a.b", true},
+		{"a.#.b", "a.x.y.b", true},
+		{"a.#.b", "a.x.y", false},
+		{"a.#.b", "b.a.b", false},
+		{"*.#", "", false},
+		{"*.#", "a", true},
+		{"*.#", "a.b.c", true},
+		{"*", "", false},
+		{"*", "a.b", false},
+		{"a.*.b", "a..b", true},
+		{"a.*", "a.", true},
+		{"*.a", ".a", true},
+		{"a.b", "a..b", false},
+		{"a", "a.", false},
+		{"a..b", "a..b", true},
+		{"a..b", "a.b", false},
+		{"a.", "a.", true},
+		{"a*", "a*", true},
+		{"a*", "ab", false},
+		{"#a.b", "#a.b", true},
+		{"#a.b", "x.b", false},
+		{"", "", true},
+		{"", "a", false},
+		{"#", long, true},
+		{"#", long + ".a", false},
+	}
+	if len(long) != MaxLength {
+		t.Fatalf("long key of %d bytes", len(long))
+	}
+	for _, test := range tests {
+		if got := Match(test.pattern, test.key); got != test.match {
+			t.Errorf("Match('%s', '%s') = %t, want %t", test.pattern, test.key, got, test.match)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		s     string
+		valid bool
+	}{
+		{"", true},
+		{"a", true},
+		{"a.b.c", true},
+		{"#", true},
+		{"a.*.#", true},
+		{"a..b", false},
+		{".a", false},
+		{"a.", false},
+		{".", false},
+		{"a#", false},
+		{"*a.b", false},
+		{"a.#*", false},
+		{strings.Repeat("a", MaxLength), true},
+		{strings.Repeat("a", MaxLength+1), false},
+	}
+	for _, test := range tests {
+		if got := ValidateKey(test.s) == nil; got != test.valid {
+			t.Errorf("ValidateKey('%s') valid = %t, want %t", test.s, got, test.valid)
+		}
+		if got := ValidatePattern(test.s) == nil; got != test.valid {
+			t.Errorf("ValidatePattern('%s') valid = %t, want %t", test.s, got, test.valid)
+		}
+		// The broker accepts any pattern up to the max length.
+		if _, err := Compile(test.s); (err == nil) != (len(test.s) <= MaxLength) {
+			t.Errorf("Compile('%s') error = %v", test.s, err)
+		}
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	for pattern, literal := range map[string]bool{"a.b": true, "": true, "a.*": false, "#": false} {
+		if got := MustCompile(pattern).Literal(); got != literal {
+			t.Errorf("Literal('%s') = %t, want %t", pattern, got, literal)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package topology
+
+import (
+	"math"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Declare the exchanges, the queues and the bindings of the topology.
+// Bindings to exchanges are declared with ExchangeBind: the destination
+// exchange receives the messages routed to it by the source exchange, and
+// routes them again with its own bindings (the routing key is unchanged).
+func (t *Topology) Declare(channel *amqp.Channel) error {
+	for _, e := range t.Exchanges {
+		var args amqp.Table
+		if e.AlternateExchange != "" {
+			args = amqp.Table{"alternate-exchange": e.AlternateExchange}
+		}
+		err := channel.ExchangeDeclare(e.Name, e.Type, true, false, false, false, args)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, q := range t.Queues {
+		_, err := channel.QueueDeclare(q.Name, true, false, false, false, arguments(q.Arguments))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, b := range t.Bindings {
+		var err error
+		if b.DestinationType == DestinationExchange {
+			err = channel.ExchangeBind(b.Destination, b.Key, b.Source, false, arguments(b.Arguments))
+		} else {
+			err = channel.QueueBind(b.Destination, b.Key, b.Source, false, arguments(b.Arguments))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JSON numbers are decoded as floats, while the broker expects integers
+// for arguments such as 'x-max-length': whole numbers are converted.
+func arguments(args amqp.Table) amqp.Table {
+	if len(args) == 0 {
+		return nil
+	}
+	converted := amqp.Table{}
+	for k, v := range args {
+		if f, ok := v.(float64); ok && f == math.Trunc(f) {
+			v = int64(f)
+		}
+		converted[k] = v
+	}
+	return converted
+}
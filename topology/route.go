@@ -0,0 +1,240 @@
+package topology
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"go-rabbit/topic"
+)
+
+// A step of the route of a message: the exchange that routes it, how the
+// message got there, and where the exchange routes it.
+type Step struct {
+	Exchange  Exchange
+	Binding   *Binding // the binding from the previous exchange (nil for the first one)
+	Alternate bool     // reached as the alternate exchange of the previous one
+	Queues    []Binding
+	Next      []*Step
+}
+
+// A route through the topology, from the exchange a message is published to.
+type Route struct {
+	First  *Step
+	Queues []string // the final queues, each receiving the message once
+}
+
+// Unroutable if the message reaches no queue.
+func (r Route) Unroutable() bool {
+	return len(r.Queues) == 0
+}
+
+// Compute the route of a message published to the exchange with the routing
+// key (and the headers, for headers exchanges), like the broker does. The
+// message is routed by each exchange at most once, even if the bindings form
+// a cycle, and it's delivered at most once to each queue. If an exchange
+// has no matching binding, the message goes to its alternate exchange.
+func (t *Topology) Route(exchange, key string, headers amqp.Table) (Route, error) {
+	e, ok := t.exchange(exchange)
+	if !ok {
+		return Route{}, fmt.Errorf("unknown exchange: '%s'", exchange)
+	}
+	// Like the broker, any routing key up to the max length is routed,
+	// even with empty words or wildcard characters.
+	if len(key) > topic.MaxLength {
+		return Route{}, fmt.Errorf("routing key longer than %d bytes", topic.MaxLength)
+	}
+
+	visited, queues := map[string]bool{}, map[string]bool{}
+	first := t.route(e, key, headers, visited, queues)
+
+	route := Route{First: first}
+	for q := range queues {
+		route.Queues = append(route.Queues, q)
+	}
+	sort.Strings(route.Queues)
+	return route, nil
+}
+
+func (t *Topology) route(e Exchange, key string, headers amqp.Table, visited, queues map[string]bool) *Step {
+	visited[e.Name] = true
+	step := &Step{Exchange: e}
+
+	matched := false
+	for i := range t.Bindings {
+		b := &t.Bindings[i]
+		if b.Source != e.Name || !matches(e.Type, *b, key, headers) {
+			continue
+		}
+		matched = true
+		if b.DestinationType == DestinationExchange {
+			next, _ := t.exchange(b.Destination)
+			if visited[next.Name] {
+				continue
+			}
+			s := t.route(next, key, headers, visited, queues)
+			s.Binding = b
+			step.Next = append(step.Next, s)
+		} else {
+			step.Queues = append(step.Queues, *b)
+			queues[b.Destination] = true
+		}
+	}
+
+	if !matched && e.AlternateExchange != "" && !visited[e.AlternateExchange] {
+		alternate, _ := t.exchange(e.AlternateExchange)
+		s := t.route(alternate, key, headers, visited, queues)
+		s.Alternate = true
+		step.Next = append(step.Next, s)
+	}
+	return step
+}
+
+// Whether the binding of an exchange of the provided type matches the
+// routing key (or the headers).
+func matches(kind string, b Binding, key string, headers amqp.Table) bool {
+	switch kind {
+	case amqp.ExchangeFanout:
+		return true
+	case amqp.ExchangeDirect:
+		return b.Key == key
+	case amqp.ExchangeTopic:
+		return topic.Match(b.Key, key)
+	case amqp.ExchangeHeaders:
+		return matchHeaders(b.Arguments, headers)
+	}
+	return false
+}
+
+// Match the arguments of a headers binding against the message headers.
+// With 'x-match' set to 'all' (the default) all the arguments must match,
+// with 'any' at least one. Arguments starting with 'x-' are not matched.
+// Like the broker, values match only if they have the same type: the
+// integer 1 doesn't match the string "1".
+func matchHeaders(args, headers amqp.Table) bool {
+	matchAny := args["x-match"] == "any"
+	for name, value := range args {
+		if strings.HasPrefix(name, "x-") {
+			continue
+		}
+		header, ok := headers[name]
+		found := ok && equalValues(header, value)
+		if matchAny && found {
+			return true
+		}
+		if !matchAny && !found {
+			return false
+		}
+	}
+	return !matchAny
+}
+
+// Compare two header values of the same type. All the integer types are
+// numbers, like the floats holding whole numbers (JSON numbers are decoded
+// as floats, and declared as integers, see arguments).
+func equalValues(a, b interface{}) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+	switch x := a.(type) {
+	case []byte:
+		y, ok := b.([]byte)
+		return ok && bytes.Equal(x, y)
+	case string, bool:
+		return a == b
+	}
+	return false
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case float32:
+		return float64(n), float64(n) == math.Trunc(float64(n))
+	case float64:
+		return n, n == math.Trunc(n)
+	}
+	return 0, false
+}
+
+// Parse the headers of a message, a comma separated list of
+// '<name>=<value>' pairs. Integers and booleans are typed values, like
+// in a message published by a client: to send them as strings they must
+// be quoted, e.g. 'code="1"'.
+func ParseHeaders(s string) (amqp.Table, error) {
+	headers := amqp.Table{}
+	if s == "" {
+		return headers, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.Split(pair, "=")
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid header: '%s'", pair)
+		}
+		headers[parts[0]] = headerValue(parts[1])
+	}
+	return headers, nil
+}
+
+func headerValue(s string) interface{} {
+	if len(s) >= 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) {
+		return s[1 : len(s)-1]
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if s == "true" || s == "false" {
+		return s == "true"
+	}
+	return s
+}
+
+// Print the route as a tree, followed by the final queues.
+func (r Route) Print(w io.Writer) {
+	printStep(w, r.First, "")
+	if r.Unroutable() {
+		fmt.Fprintln(w, "unroutable: no queue receives the message")
+		return
+	}
+	fmt.Fprintf(w, "queues: %s\n", strings.Join(r.Queues, ", "))
+}
+
+func printStep(w io.Writer, s *Step, indent string) {
+	switch {
+	case s.Binding != nil:
+		fmt.Fprintf(w, "%s-> exchange '%s' (%s), binding %s\n", indent, s.Exchange.Name, s.Exchange.Type, s.Binding)
+	case s.Alternate:
+		fmt.Fprintf(w, "%s-> alternate exchange '%s' (%s)\n", indent, s.Exchange.Name, s.Exchange.Type)
+	default:
+		fmt.Fprintf(w, "%sexchange '%s' (%s)\n", indent, s.Exchange.Name, s.Exchange.Type)
+	}
+	indent += "   "
+	for _, b := range s.Queues {
+		fmt.Fprintf(w, "%s-> queue '%s', binding %s\n", indent, b.Destination, b)
+	}
+	for _, next := range s.Next {
+		printStep(w, next, indent)
+	}
+}
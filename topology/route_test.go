@@ -0,0 +1,99 @@
+package topology
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestMatchHeaders(t *testing.T) {
+	tests := []struct {
+		args    amqp.Table
+		headers amqp.Table
+		match   bool
+	}{
+		{amqp.Table{"severity": "error"}, amqp.Table{"severity": "error"}, true},
+		{amqp.Table{"severity": "error"}, amqp.Table{"severity": "warn"}, false},
+		{amqp.Table{"severity": "error"}, amqp.Table{}, false},
+		{amqp.Table{"code": int64(1)}, amqp.Table{"code": "1"}, false},
+		{amqp.Table{"code": "1"}, amqp.Table{"code": int64(1)}, false},
+		{amqp.Table{"code": float64(1)}, amqp.Table{"code": int32(1)}, true},
+		{amqp.Table{"code": 1.5}, amqp.Table{"code": 1.5}, false},
+		{amqp.Table{"eu": true}, amqp.Table{"eu": "true"}, false},
+		{amqp.Table{"eu": true}, amqp.Table{"eu": true}, true},
+		{amqp.Table{"trace": []byte("abc")}, amqp.Table{"trace": []byte("abc")}, true},
+		{amqp.Table{"x-match": "all", "a": "1", "b": "2"}, amqp.Table{"a": "1"}, false},
+		{amqp.Table{"x-match": "any", "a": "1", "b": "2"}, amqp.Table{"a": "1"}, true},
+		{amqp.Table{"x-match": "any", "a": "1"}, amqp.Table{"a": int64(1)}, false},
+	}
+	for _, test := range tests {
+		if got := matchHeaders(test.args, test.headers); got != test.match {
+			t.Errorf("matchHeaders(%v, %v) = %t, want %t", test.args, test.headers, got, test.match)
+		}
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	headers, err := ParseHeaders(`severity=error,code=503,eu=true,zip="01234"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := amqp.Table{"severity": "error", "code": int64(503), "eu": true, "zip": "01234"}
+	if !reflect.DeepEqual(headers, want) {
+		t.Errorf("parsed %v, want %v", headers, want)
+	}
+
+	for _, s := range []string{"severity", "=error", "a=1=2", "a=1,"} {
+		if _, err := ParseHeaders(s); err == nil {
+			t.Errorf("ParseHeaders('%s') accepted", s)
+		}
+	}
+}
+
+// The broker routes any key up to the max length, and accepts any binding
+// key: empty words and wildcard characters are literal.
+func TestRouteBrokerKeys(t *testing.T) {
+	topo := &Topology{
+		Exchanges: []Exchange{
+			{Name: "direct", Type: amqp.ExchangeDirect},
+			{Name: "fanout", Type: amqp.ExchangeFanout},
+			{Name: "topic", Type: amqp.ExchangeTopic},
+		},
+		Queues: []Queue{{Name: "q"}},
+		Bindings: []Binding{
+			{Source: "direct", Destination: "q", Key: "err#1"},
+			{Source: "fanout", Destination: "q", Key: "a..b"},
+			{Source: "topic", Destination: "q", Key: "nginx.*.error"},
+		},
+	}
+	err := topo.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		exchange string
+		key      string
+		routed   bool
+	}{
+		{"direct", "err#1", true},
+		{"direct", "err", false},
+		{"fanout", "a*", true},
+		{"topic", "nginx..error", true},
+		{"topic", "nginx.error", false},
+	}
+	for _, test := range tests {
+		route, err := topo.Route(test.exchange, test.key, nil)
+		if err != nil {
+			t.Errorf("Route('%s', '%s'): %s", test.exchange, test.key, err)
+			continue
+		}
+		if route.Unroutable() == test.routed {
+			t.Errorf("Route('%s', '%s') routed = %t, want %t", test.exchange, test.key, !route.Unroutable(), test.routed)
+		}
+	}
+	if _, err := topo.Route("topic", strings.Repeat("a", 256), nil); err == nil {
+		t.Error("routing key longer than 255 bytes routed")
+	}
+}
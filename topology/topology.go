@@ -0,0 +1,161 @@
+// Package topology describes a set of exchanges, queues and bindings,
+// read from a JSON file, that can be declared on the broker or used to
+// compute offline where a message would be routed.
+package topology
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"go-rabbit/topic"
+)
+
+// The kinds of binding destinations.
+const (
+	DestinationQueue    = "queue"
+	DestinationExchange = "exchange"
+)
+
+// A topology, e.g. a topic exchange fanning into per-team direct exchanges:
+//
+//	{
+//		"exchanges": [
+//			{ "name": "logs-topic-exchange", "type": "topic" },
+//			{ "name": "team-web", "type": "direct", "alternate_exchange": "team-web-unrouted" },
+//			{ "name": "team-web-unrouted", "type": "fanout" }
+//		],
+//		"queues": [{ "name": "web-errors" }, { "name": "web-unrouted" }],
+//		"bindings": [
+//			{ "source": "logs-topic-exchange", "destination": "team-web", "destination_type": "exchange", "key": "nginx.#" },
+//			{ "source": "team-web", "destination": "web-errors", "key": "nginx.error" },
+//			{ "source": "team-web-unrouted", "destination": "web-unrouted" }
+//		]
+//	}
+//
+// Exchanges and queues are durable. The destination type of a binding is
+// a queue, unless specified.
+type Topology struct {
+	Exchanges []Exchange `json:"exchanges"`
+	Queues    []Queue    `json:"queues"`
+	Bindings  []Binding  `json:"bindings"`
+}
+
+type Exchange struct {
+	Name              string `json:"name"`
+	Type              string `json:"type"`
+	AlternateExchange string `json:"alternate_exchange,omitempty"`
+}
+
+type Queue struct {
+	Name      string     `json:"name"`
+	Arguments amqp.Table `json:"arguments,omitempty"`
+}
+
+// A binding from an exchange to a queue or to another exchange. Headers
+// exchanges use the arguments (with 'x-match') instead of the key.
+type Binding struct {
+	Source          string     `json:"source"`
+	Destination     string     `json:"destination"`
+	DestinationType string     `json:"destination_type,omitempty"`
+	Key             string     `json:"key"`
+	Arguments       amqp.Table `json:"arguments,omitempty"`
+}
+
+func (b Binding) String() string {
+	if len(b.Arguments) > 0 {
+		return fmt.Sprintf("%v", map[string]interface{}(b.Arguments))
+	}
+	return fmt.Sprintf("'%s'", b.Key)
+}
+
+// Load a topology from a JSON file, and validate it.
+func Load(path string) (*Topology, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var t Topology
+	err = json.Unmarshal(data, &t)
+	if err != nil {
+		return nil, err
+	}
+	return &t, t.Validate()
+}
+
+// Validate the topology: names must be unique, and bindings and alternate
+// exchanges must refer to exchanges and queues of the topology. The keys of
+// the bindings from topic exchanges are patterns, up to the max length.
+func (t *Topology) Validate() error {
+	exchanges := map[string]bool{}
+	for _, e := range t.Exchanges {
+		if e.Name == "" || exchanges[e.Name] {
+			return fmt.Errorf("missing or duplicated exchange name: '%s'", e.Name)
+		}
+		if strings.HasPrefix(e.Name, "amq.") {
+			return fmt.Errorf("reserved exchange name: '%s'", e.Name)
+		}
+		switch e.Type {
+		case amqp.ExchangeDirect, amqp.ExchangeFanout, amqp.ExchangeTopic, amqp.ExchangeHeaders:
+		default:
+			return fmt.Errorf("invalid type of exchange '%s': '%s'", e.Name, e.Type)
+		}
+		exchanges[e.Name] = true
+	}
+	for _, e := range t.Exchanges {
+		if e.AlternateExchange != "" && !exchanges[e.AlternateExchange] {
+			return fmt.Errorf("unknown alternate exchange of '%s': '%s'", e.Name, e.AlternateExchange)
+		}
+	}
+
+	queues := map[string]bool{}
+	for _, q := range t.Queues {
+		if q.Name == "" || queues[q.Name] {
+			return fmt.Errorf("missing or duplicated queue name: '%s'", q.Name)
+		}
+		queues[q.Name] = true
+	}
+
+	for i := range t.Bindings {
+		b := &t.Bindings[i]
+		if b.DestinationType == "" {
+			b.DestinationType = DestinationQueue
+		}
+		if !exchanges[b.Source] {
+			return fmt.Errorf("unknown binding source: '%s'", b.Source)
+		}
+		switch b.DestinationType {
+		case DestinationQueue:
+			if !queues[b.Destination] {
+				return fmt.Errorf("unknown binding destination queue: '%s'", b.Destination)
+			}
+		case DestinationExchange:
+			if !exchanges[b.Destination] {
+				return fmt.Errorf("unknown binding destination exchange: '%s'", b.Destination)
+			}
+		default:
+			return fmt.Errorf("invalid binding destination type: '%s'", b.DestinationType)
+		}
+		if source, _ := t.exchange(b.Source); source.Type == amqp.ExchangeTopic {
+			if _, err := topic.Compile(b.Key); err != nil {
+				return fmt.Errorf("invalid key of binding from '%s' to '%s': %s", b.Source, b.Destination, err)
+			}
+		}
+		if match, ok := b.Arguments["x-match"]; ok && match != "all" && match != "any" {
+			return fmt.Errorf("invalid x-match of binding from '%s' to '%s': '%v'", b.Source, b.Destination, match)
+		}
+	}
+	return nil
+}
+
+func (t *Topology) exchange(name string) (Exchange, bool) {
+	for _, e := range t.Exchanges {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return Exchange{}, false
+}